@@ -9,6 +9,7 @@ require (
 	github.com/emer/etable v1.0.33
 	github.com/emer/leabra v1.1.34
 	github.com/emer/vision v1.1.11
+	github.com/go-audio/audio v1.0.0
 	github.com/goki/gi v1.2.10
 	github.com/goki/ki v1.1.3
 	github.com/goki/mat32 v1.0.9
@@ -0,0 +1,205 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/goki/ki/kit"
+)
+
+// NoiseType is the type of noise added to the sound by augmentation
+type NoiseType int
+
+var KiT_NoiseType = kit.Enums.AddEnum(NoiseTypeN, kit.NotBitFlag, nil)
+
+const (
+	NoNoise NoiseType = iota
+	WhiteNoise
+	PinkNoise
+	NoiseTypeN
+)
+
+//go:generate stringer -type=NoiseType
+
+// Augment holds the parameters for the random augmentation applied to each sound after it is loaded,
+// so that every presentation of a sequence is a somewhat different talker, tempo and recording.
+// The values are drawn uniformly from +/- the maximums for each sound, from a random source seeded
+// from Seed and the run so the augmentation is reproducible.
+type Augment struct {
+	On        bool       `desc:"apply the augmentation to each sound as it is loaded"`
+	Seed      int64      `desc:"seed for the augmentation draws - the run number is added so each run differs"`
+	PitchSemi float64    `def:"2" desc:"maximum pitch shift in semitones - shifts the pitch without changing the duration"`
+	Stretch   float64    `def:"0.1" desc:"maximum time stretch, as a proportion of the duration, e.g. 0.1 is 0.9 to 1.1 times as long - changes the tempo without changing the pitch and the CV times are rescaled to match"`
+	GainDb    float64    `def:"6" desc:"maximum change in level in decibels"`
+	Noise     NoiseType  `desc:"type of noise to add"`
+	SNRMin    float64    `def:"10" desc:"minimum signal to noise ratio in decibels of the added noise"`
+	SNRMax    float64    `def:"30" desc:"maximum signal to noise ratio in decibels of the added noise"`
+	CurPitch  float64    `inactive:"+" desc:"pitch shift in semitones of the current sound"`
+	CurStr    float64    `inactive:"+" desc:"time stretch factor of the current sound, 1 is no change"`
+	CurGain   float64    `inactive:"+" desc:"gain in decibels of the current sound"`
+	CurSNR    float64    `inactive:"+" desc:"signal to noise ratio in decibels of the noise added to the current sound"`
+	CurNoise  int64      `view:"-" desc:"seed of the noise added to the current sound -- the channel is added, so every pathway that loads the same channel gets the same noise"`
+	Rand      *rand.Rand `view:"-" desc:"random source for the augmentation draws"`
+}
+
+// Defaults sets the default parameters, with augmentation off
+func (au *Augment) Defaults() {
+	au.On = false
+	au.PitchSemi = 2
+	au.Stretch = 0.1
+	au.GainDb = 6
+	au.Noise = NoNoise
+	au.SNRMin = 10
+	au.SNRMax = 30
+}
+
+// Init seeds the random source for the run
+func (au *Augment) Init(run int) {
+	au.Rand = rand.New(rand.NewSource(au.Seed + int64(run)))
+}
+
+// uniform returns a random value between -max and max
+func (au *Augment) uniform(max float64) float64 {
+	return max * (2*au.Rand.Float64() - 1)
+}
+
+// Draw draws the augmentation values for the next sound
+func (au *Augment) Draw() {
+	if au.Rand == nil {
+		au.Init(0)
+	}
+	au.CurPitch = au.uniform(au.PitchSemi)
+	au.CurStr = 1 + au.uniform(au.Stretch)
+	au.CurGain = au.uniform(au.GainDb)
+	au.CurSNR = au.SNRMin + au.Rand.Float64()*(au.SNRMax-au.SNRMin)
+	au.CurNoise = au.Rand.Int63()
+}
+
+// Apply applies the current draw to channel ch of the signal and returns the augmented signal --
+// the same draw can be applied to each pathway at its own sample rate
+func (au *Augment) Apply(sig []float32, sr int, ch int) []float32 {
+	ratio := math.Pow(2, au.CurPitch/12)
+	if ratio != 1 {
+		// resampling by ratio shifts the pitch and shortens the sound by ratio, so stretch it back
+		sig = Resample(sig, ratio)
+	}
+	sig = TimeStretch(sig, ratio*au.CurStr, sr)
+
+	g := float32(math.Pow(10, au.CurGain/20))
+	for i := range sig {
+		sig[i] *= g
+	}
+
+	if au.Noise != NoNoise {
+		au.AddNoise(sig, ch)
+	}
+	return sig
+}
+
+// AddNoise adds white or pink noise to channel ch of the signal at the current signal to noise ratio
+func (au *Augment) AddNoise(sig []float32, ch int) {
+	rnd := rand.New(rand.NewSource(au.CurNoise + int64(ch)))
+	noise := make([]float64, len(sig))
+	var b0, b1, b2, b3, b4, b5, b6 float64
+	for i := range noise {
+		w := 2*rnd.Float64() - 1
+		if au.Noise == WhiteNoise {
+			noise[i] = w
+			continue
+		}
+		// pink noise filter (Paul Kellet's refined method)
+		b0 = 0.99886*b0 + w*0.0555179
+		b1 = 0.99332*b1 + w*0.0750759
+		b2 = 0.96900*b2 + w*0.1538520
+		b3 = 0.86650*b3 + w*0.3104856
+		b4 = 0.55000*b4 + w*0.5329522
+		b5 = -0.7616*b5 - w*0.0168980
+		noise[i] = b0 + b1 + b2 + b3 + b4 + b5 + b6 + w*0.5362
+		b6 = w * 0.115926
+	}
+	sigPow := 0.0
+	noisePow := 0.0
+	for i, v := range sig {
+		sigPow += float64(v) * float64(v)
+		noisePow += noise[i] * noise[i]
+	}
+	if sigPow == 0 || noisePow == 0 {
+		return
+	}
+	g := math.Sqrt(sigPow / noisePow / math.Pow(10, au.CurSNR/10))
+	for i := range sig {
+		sig[i] += float32(g * noise[i])
+	}
+}
+
+// Resample returns the signal read at steps of ratio samples, using linear interpolation -
+// the result has len(sig) / ratio samples and all frequencies multiplied by ratio
+func Resample(sig []float32, ratio float64) []float32 {
+	n := int(float64(len(sig)) / ratio)
+	out := make([]float32, n)
+	for i := range out {
+		t := float64(i) * ratio
+		j := int(t)
+		if j+1 >= len(sig) {
+			out[i] = sig[len(sig)-1]
+			continue
+		}
+		f := float32(t - float64(j))
+		out[i] = sig[j]*(1-f) + sig[j+1]*f
+	}
+	return out
+}
+
+// TimeStretch returns the signal stretched in time by factor (> 1 is longer) without changing the pitch,
+// using the waveform similarity overlap-add method (WSOLA, Verhelst & Roelands, 1993)
+func TimeStretch(sig []float32, factor float64, sr int) []float32 {
+	if factor == 1 || len(sig) == 0 {
+		return sig
+	}
+	n := sr * 30 / 1000 // 30 ms frames
+	hop := n / 2        // output hop, with 50% overlap the hann windows sum to 1
+	tol := sr * 10 / 1000
+	win := make([]float32, n)
+	for i := range win {
+		win[i] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n)))
+	}
+
+	outLen := int(float64(len(sig)) * factor)
+	out := make([]float32, outLen+n)
+	prev := 0
+	for k := 0; k*hop < outLen; k++ {
+		pos := int(float64(k*hop) / factor) // nominal input position
+		best := pos
+		if k > 0 {
+			// choose the frame near the nominal position most like the natural continuation of the previous frame,
+			// comparing every 4th sample of the overlapping half
+			nat := prev + hop
+			bestC := math.Inf(-1)
+			for d := -tol; d <= tol; d++ {
+				p := pos + d
+				if p < 0 || p+hop > len(sig) || nat+hop > len(sig) {
+					continue
+				}
+				c := 0.0
+				for i := 0; i < hop; i += 4 {
+					c += float64(sig[nat+i]) * float64(sig[p+i])
+				}
+				if c > bestC {
+					bestC = c
+					best = p
+				}
+			}
+		}
+		for i := 0; i < n && best+i < len(sig); i++ {
+			if best+i >= 0 {
+				out[k*hop+i] += win[i] * sig[best+i]
+			}
+		}
+		prev = best
+	}
+	return out[:outLen]
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FeatCache is an on-disk cache of the front end output of the segments of each sound file, so that the
+// fft, filterbank and gabor processing of a file is only done once for each setting of the params.
+// The cache files are keyed by the sound file, the params of the pathway and the silence added at the start,
+// which is quantized so there are a limited number of versions of each file.
+type FeatCache struct {
+	On             bool   `desc:"cache the front end output of the segments of each sound file on disk -- sounds that differ each time they are loaded, i.e. augmented, rendered or continuous sounds, are not cached"`
+	Path           string `desc:"directory of the cache files, created if needed"`
+	SilenceQuantMs int    `def:"5" desc:"the random silence added at the start of each sound is rounded to a multiple of this many milliseconds when caching, so there are at most SilenceMax / SilenceQuantMs + 1 cached versions of each file"`
+}
+
+// Defaults sets the default parameters, with caching off
+func (fc *FeatCache) Defaults() {
+	fc.On = false
+	fc.Path = "featcache"
+	fc.SilenceQuantMs = 5
+}
+
+// Quantize rounds the milliseconds of silence to a multiple of SilenceQuantMs
+func (fc *FeatCache) Quantize(ms float64) float64 {
+	if fc.SilenceQuantMs <= 1 {
+		return math.Round(ms)
+	}
+	q := float64(fc.SilenceQuantMs)
+	return math.Round(ms/q) * q
+}
+
+// FileName returns the cache file of the pathway for the sound file -- the key hashes the path, size and
+// modification time of the sound file, the params of the pathway and its front end, the milliseconds of silence and any
+// other processing of the sound, in extra
+func (fc *FeatCache) FileName(sndFile string, se *SndEnv, msSilence float64, extra string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%v\n", sndFile)
+	if fi, err := os.Stat(sndFile); err == nil {
+		fmt.Fprintf(h, "%v %v\n", fi.Size(), fi.ModTime().UnixNano())
+	}
+	fmt.Fprintf(h, "%v %v %v\n", se.FrontEnd, se.GborPoolsY, se.GborPoolsX)
+	fmt.Fprintf(h, "%+v\n%+v\n%+v\n%+v\n%+v\n%+v\n%+v\n%+v\n", se.Params, se.Dft, se.Mel, se.Gbor, se.Kwta, se.NeighInhib, se.Feats, se.Norm)
+	fmt.Fprintf(h, "%+v\n", feParams(se.FE))
+	fmt.Fprintf(h, "%v\n%v\n", msSilence, extra)
+	base := strings.TrimSuffix(filepath.Base(sndFile), filepath.Ext(sndFile))
+	return filepath.Join(fc.Path, se.Nm+"_"+base+"_"+hex.EncodeToString(h.Sum(nil))[:16]+".gob")
+}
+
+// feParams returns the front end with its own params, e.g. the gain of the mfcc, without the values derived from them
+func feParams(fe FrontEnd) interface{} {
+	if gt, ok := fe.(*GammatoneFrontEnd); ok {
+		p := *gt
+		p.Filters = nil // from the order, bandwidth and filterbank params
+		return p
+	}
+	return fe
+}
+
+// SegCache holds the cached segments of the current sound of a SndEnv -- either all of the segments
+// read from the cache file, or the segments processed so far, which are written to the file once
+// the last segment is processed. The segments of a prefetched sound are kept in memory even if not cached.
+type SegCache struct {
+	File   string      `desc:"cache file of the current sound, empty if the sound is not cached"`
+	Memory bool        `desc:"keep the processed segments even if the sound is not cached on disk"`
+	Hit    bool        `desc:"all of the segments are in the cache, read from File or precomputed, rather than processed as they are used"`
+	Shape  []int       `desc:"shape of the GborOutput and GborKwta of each segment"`
+	Output [][]float32 `desc:"GborOutput values of each segment"`
+	Kwta   [][]float32 `desc:"GborKwta values of each segment"`
+	Feats  [][]float32 `desc:"FeatOutput values of each segment"`
+}
+
+// Close turns off caching for the current sound
+func (sc *SegCache) Close() {
+	sc.File = ""
+	sc.Memory = false
+	sc.Hit = false
+	sc.Shape = nil
+	sc.Output = nil
+	sc.Kwta = nil
+	sc.Feats = nil
+}
+
+// Open sets the cache file for the current sound of se, after se.Init, and reads the segments
+// from the file if it has all of them
+func (sc *SegCache) Open(fn string, se *SndEnv) {
+	sc.Close()
+	sc.File = fn
+	fp, err := os.Open(fn)
+	if err != nil {
+		return // not cached yet
+	}
+	defer fp.Close()
+	var rd SegCache
+	if err := gob.NewDecoder(fp).Decode(&rd); err != nil {
+		log.Printf("SegCache: error reading %v -- %v\n", fn, err)
+		return
+	}
+	if len(rd.Output) != se.SegCnt || len(rd.Kwta) != se.SegCnt || len(rd.Feats) != se.SegCnt || !sameShape(rd.Shape, se.GborOutput.Shapes()) {
+		return
+	}
+	sc.Hit = true
+	sc.Shape = rd.Shape
+	sc.Output = rd.Output
+	sc.Kwta = rd.Kwta
+	sc.Feats = rd.Feats
+}
+
+// Segment copies segment seg from the cache into GborOutput, GborKwta and FeatOutput of se, returning false if not cached
+func (sc *SegCache) Segment(se *SndEnv, seg int) bool {
+	if !sc.Hit || seg < 0 || seg >= len(sc.Output) {
+		return false
+	}
+	copy(se.GborOutput.Values, sc.Output[seg])
+	copy(se.GborKwta.Values, sc.Kwta[seg])
+	copy(se.FeatOutput.Values, sc.Feats[seg])
+	return true
+}
+
+// Add adds the just processed segment of se, writing the cache file once the last segment is added
+func (sc *SegCache) Add(se *SndEnv) {
+	if (sc.File == "" && !sc.Memory) || sc.Hit || se.Segment != len(sc.Output) {
+		return
+	}
+	sc.Shape = se.GborOutput.Shapes()
+	sc.Output = append(sc.Output, append([]float32{}, se.GborOutput.Values...))
+	sc.Kwta = append(sc.Kwta, append([]float32{}, se.GborKwta.Values...))
+	sc.Feats = append(sc.Feats, append([]float32{}, se.FeatOutput.Values...))
+	if sc.File != "" && len(sc.Output) == se.SegCnt {
+		if err := sc.Write(); err != nil {
+			log.Printf("SegCache: error writing %v -- %v\n", sc.File, err)
+		}
+	}
+}
+
+// Write writes the segments to File, via a temporary file so that a partly written file is never read
+func (sc *SegCache) Write() error {
+	if err := os.MkdirAll(filepath.Dir(sc.File), 0755); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%v.%d.tmp", sc.File, os.Getpid())
+	fp, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(fp).Encode(sc)
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, sc.File)
+}
+
+// sameShape returns true if the shapes are the same
+func sameShape(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/etable/etensor"
+)
+
+// Features are optional feature planes computed from the filterbank output of each segment, alongside the
+// front end output -- the positive and negative spectral flux of the bands, i.e. the onsets and offsets,
+// and the broadband amplitude envelope. Each is a column of every pool, with 2 rows like the gabor filters.
+type Features struct {
+	Flux     bool    `desc:"spectral flux column -- the step to step increase (first row) and decrease (second row) of the filterbank bands of the pool"`
+	Envelope bool    `desc:"broadband envelope column -- the mean of all of the filterbank bands (first row) and its step to step change (second row), the same for every frequency pool"`
+	FluxGain float32 `def:"4" desc:"multiplier of the flux -- the output is clipped to 1"`
+	EnvGain  float32 `def:"1" desc:"multiplier of the envelope and its change -- the output is clipped to 1"`
+}
+
+// Defaults sets the gains -- the pathway sets which features are on
+func (ft *Features) Defaults() {
+	ft.FluxGain = 4
+	ft.EnvGain = 1
+}
+
+// Cols returns the number of feature columns
+func (ft *Features) Cols() int {
+	n := 0
+	if ft.Flux {
+		n++
+	}
+	if ft.Envelope {
+		n++
+	}
+	return n
+}
+
+// clip1 clips the value to 1
+func clip1(v float32) float32 {
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// ApplyFeatures computes the feature planes of each channel into FeatOutput, from the filterbank output of the
+// segment in MelFBankSegment. Each Y pool covers an equal part of the bands and each X pool an equal part
+// of the steps of the segment, not counting the border steps -- the change at the first step of the segment
+// is from the border step before it.
+func (se *SndEnv) ApplyFeatures() {
+	if se.Feats.Cols() == 0 {
+		return
+	}
+	se.FeatOutput.SetZeros()
+	nb := se.Mel.FBank.NFilters
+	py := se.GborPoolsY
+	px := se.GborPoolsX
+	for ch := 0; ch < se.Channels(); ch++ {
+		fb := func(s, b int) float32 {
+			if s < 0 {
+				s = 0
+			}
+			return se.MelFBankSegment.Value([]int{s, b, ch})
+		}
+		env := func(s int) float32 {
+			sum := float32(0)
+			for b := 0; b < nb; b++ {
+				sum += fb(s, b)
+			}
+			return sum / float32(nb)
+		}
+		for x := 0; x < px; x++ {
+			st := se.Params.BorderSteps + x*se.Params.SegmentSteps/px
+			end := se.Params.BorderSteps + (x+1)*se.Params.SegmentSteps/px
+			if end <= st {
+				end = st + 1
+			}
+			col := 0
+			if se.Feats.Flux {
+				for y := 0; y < py; y++ {
+					bst := y * nb / py
+					bend := (y + 1) * nb / py
+					if bend <= bst {
+						bend = bst + 1
+					}
+					if bend > nb {
+						bend = nb
+					}
+					on := float32(0)
+					off := float32(0)
+					for s := st; s < end; s++ {
+						for b := bst; b < bend; b++ {
+							d := fb(s, b) - fb(s-1, b)
+							if d > 0 {
+								on += d
+							} else {
+								off -= d
+							}
+						}
+					}
+					n := float32((end - st) * (bend - bst))
+					if n > 0 {
+						se.FeatOutput.Set([]int{ch, y, x, 0, col}, clip1(se.Feats.FluxGain*on/n))
+						se.FeatOutput.Set([]int{ch, y, x, 1, col}, clip1(se.Feats.FluxGain*off/n))
+					}
+				}
+				col++
+			}
+			if se.Feats.Envelope {
+				lev := float32(0)
+				chg := float32(0)
+				for s := st; s < end; s++ {
+					e := env(s)
+					lev += e
+					d := e - env(s-1)
+					if d < 0 {
+						d = -d
+					}
+					chg += d
+				}
+				n := float32(end - st)
+				for y := 0; y < py; y++ {
+					se.FeatOutput.Set([]int{ch, y, x, 0, col}, clip1(se.Feats.EnvGain*lev/n))
+					se.FeatOutput.Set([]int{ch, y, x, 1, col}, clip1(se.Feats.EnvGain*chg/n))
+				}
+				col++
+			}
+		}
+	}
+}
+
+// FeatInput returns the feature planes of channel ch, for a feature input layer of their own
+func (se *SndEnv) FeatInput(ch int) *etensor.Float32 {
+	if ch >= se.Channels() {
+		ch = se.Channels() - 1
+	}
+	return se.FeatOutput.SubSpace([]int{ch}).(*etensor.Float32)
+}
+
+// InputWithFeats returns the output of channel ch with the feature columns added after the columns of
+// the gabor filters in each pool, for the input layer of the pathway
+func (se *SndEnv) InputWithFeats(ch int) *etensor.Float32 {
+	out := se.Output(ch)
+	if se.Feats.Cols() == 0 {
+		return out
+	}
+	ft := se.FeatInput(ch)
+	nf := out.Dim(3)
+	nc := ft.Dim(3)
+	se.InputCat.SetShape([]int{out.Dim(0), out.Dim(1), out.Dim(2), nf + nc}, nil, nil)
+	vals := se.InputCat.Values
+	i, fi, ci := 0, 0, 0
+	for p := 0; p < out.Dim(0)*out.Dim(1)*out.Dim(2); p++ {
+		i += copy(vals[i:], out.Values[fi:fi+nf])
+		i += copy(vals[i:], ft.Values[ci:ci+nc])
+		fi += nf
+		ci += nc
+	}
+	return &se.InputCat
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/emer/auditory/agabor"
+	"github.com/goki/ki/kit"
+)
+
+// FrontEndType is the auditory preprocessing that turns the sound into the input of the A1 and R layers
+type FrontEndType int
+
+var KiT_FrontEndType = kit.Enums.AddEnum(FrontEndTypeN, kit.NotBitFlag, nil)
+
+const (
+	MelGabor  FrontEndType = iota // dft power, mel filterbank, gabor filters
+	Mfcc                          // mel frequency cepstral coefficients with their deltas and delta-deltas
+	Gammatone                     // dft power, gammatone filters spaced on the ERB scale (a cochleagram), gabor filters
+	FrontEndTypeN
+)
+
+//go:generate stringer -type=FrontEndType
+
+func (ft FrontEndType) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ft) }
+func (ft *FrontEndType) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ft, b) }
+
+// FrontEnd computes the output of a segment of sound for one channel, in GborOutput of the SndEnv,
+// which is shaped [channel, GborPoolsY, GborPoolsX, 2, Gbor.NFilters] to match the input layers.
+// The SndEnv does the windowing of the signal and the kwta of the output.
+type FrontEnd interface {
+	// Type returns the type of the front end
+	Type() FrontEndType
+
+	// Init sets up the front end, called by SndEnv.Init once the sample counts are known but before
+	// the mel filters and segment tensors are set up
+	Init(se *SndEnv)
+
+	// Step computes the features of the window of channel ch, step of the segment, from se.Window
+	Step(se *SndEnv, ch, step int)
+
+	// Output computes the output of channel ch from the features of all the steps of the segment
+	Output(se *SndEnv, ch int)
+}
+
+// NewFrontEnd returns a front end of the type with default params
+func NewFrontEnd(ft FrontEndType) FrontEnd {
+	switch ft {
+	case Mfcc:
+		mf := &MfccFrontEnd{}
+		mf.Defaults()
+		return mf
+	case Gammatone:
+		gt := &GammatoneFrontEnd{}
+		gt.Defaults()
+		return gt
+	}
+	return &MelGaborFrontEnd{}
+}
+
+// dftPower computes the power spectrum of the window into se.Power and se.PowerSegment
+func dftPower(se *SndEnv, ch, step int) {
+	se.Fft.Reset(se.Params.WinSamples)
+	se.Dft.Filter(int(ch), int(step), &se.Window, se.FirstStep, se.Params.WinSamples, se.FftCoefs, se.Fft, &se.Power, &se.LogPower, &se.PowerSegment, &se.LogPowerSegment)
+}
+
+// gaborOutput convolves the gabor filters with the filterbank output of the segment in MelFBankSegment
+func gaborOutput(se *SndEnv, ch int) {
+	agabor.Conv(ch, se.Gbor, se.Params.SegmentSteps, se.Params.BorderSteps, &se.GborOutput, se.Mel.FBank.NFilters, &se.GborFilters, &se.MelFBankSegment)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// Mel + Gabor
+
+// MelGaborFrontEnd is the original front end - the mel filterbank of the dft power, convolved with gabor filters
+type MelGaborFrontEnd struct {
+}
+
+func (mg *MelGaborFrontEnd) Type() FrontEndType { return MelGabor }
+func (mg *MelGaborFrontEnd) Init(se *SndEnv)    {}
+
+func (mg *MelGaborFrontEnd) Step(se *SndEnv, ch, step int) {
+	dftPower(se, ch, step)
+	se.Mel.Filter(int(ch), int(step), &se.Window, &se.MelFilters, &se.Power, &se.MelFBankSegment, &se.MelFBank, &se.MfccDctSegment, &se.MfccDct)
+}
+
+func (mg *MelGaborFrontEnd) Output(se *SndEnv, ch int) {
+	gaborOutput(se, ch)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// MFCC
+
+// MfccFrontEnd uses the mel frequency cepstral coefficients, computed by the mel params, and their deltas.
+// Each Y pool of the output is a coefficient, starting from the second (the first is the log energy), and each X pool
+// is an equal part of the segment (not counting the border steps) over which the values are averaged. Within a pool
+// the first row is the positive part and the second row the negative part of the coefficient (column 0),
+// the delta (column 1) and the delta-delta (column 2) - any further columns are zero.
+type MfccFrontEnd struct {
+	Gain     float32 `def:"0.25" desc:"multiplier of the coefficients -- the output is clipped to 1"`
+	DeltaWin int     `def:"2" desc:"number of steps on each side used in the regression for the deltas -- the border steps must be at least twice this for the delta-deltas of the first and last steps"`
+}
+
+func (mf *MfccFrontEnd) Defaults() {
+	mf.Gain = 0.25
+	mf.DeltaWin = 2
+}
+
+func (mf *MfccFrontEnd) Type() FrontEndType { return Mfcc }
+
+func (mf *MfccFrontEnd) Init(se *SndEnv) {
+	se.Mel.CompMfcc = true
+}
+
+func (mf *MfccFrontEnd) Step(se *SndEnv, ch, step int) {
+	dftPower(se, ch, step)
+	se.Mel.Filter(int(ch), int(step), &se.Window, &se.MelFilters, &se.Power, &se.MelFBankSegment, &se.MelFBank, &se.MfccDctSegment, &se.MfccDct)
+}
+
+// deltas returns the regression deltas of the values, over DeltaWin steps on each side, clamped at the ends
+func (mf *MfccFrontEnd) deltas(vals []float32) []float32 {
+	n := len(vals)
+	dl := make([]float32, n)
+	norm := float32(0)
+	for k := 1; k <= mf.DeltaWin; k++ {
+		norm += float32(2 * k * k)
+	}
+	at := func(i int) float32 {
+		if i < 0 {
+			i = 0
+		} else if i >= n {
+			i = n - 1
+		}
+		return vals[i]
+	}
+	for i := range vals {
+		for k := 1; k <= mf.DeltaWin; k++ {
+			dl[i] += float32(k) * (at(i+k) - at(i-k))
+		}
+		dl[i] /= norm
+	}
+	return dl
+}
+
+func (mf *MfccFrontEnd) Output(se *SndEnv, ch int) {
+	se.GborOutput.SubSpace([]int{ch}).SetZeros()
+	nsteps := se.Params.SegmentStepsTotal
+	py := se.GborPoolsY
+	px := se.GborPoolsX
+	nf := se.Gbor.NFilters
+	if nf > 3 {
+		nf = 3
+	}
+	for y := 0; y < py && y+1 < se.Mel.FBank.NFilters; y++ {
+		coef := make([]float32, nsteps)
+		for s := range coef {
+			coef[s] = se.MfccDctSegment.Value([]int{s, y + 1, ch})
+		}
+		dl := mf.deltas(coef)
+		feats := [][]float32{coef, dl, mf.deltas(dl)}
+		for x := 0; x < px; x++ {
+			st := se.Params.BorderSteps + x*se.Params.SegmentSteps/px
+			end := se.Params.BorderSteps + (x+1)*se.Params.SegmentSteps/px
+			if end <= st {
+				end = st + 1
+			}
+			for f := 0; f < nf; f++ {
+				sum := float32(0)
+				for s := st; s < end; s++ {
+					sum += feats[f][s]
+				}
+				v := mf.Gain * sum / float32(end-st)
+				row := 0
+				if v < 0 {
+					row = 1
+					v = -v
+				}
+				if v > 1 {
+					v = 1
+				}
+				se.GborOutput.Set([]int{ch, y, x, row, f}, v)
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// Gammatone
+
+// GammatoneFrontEnd approximates a gammatone filterbank by weighting the dft power with the magnitude response
+// of 4th order gammatone filters, with center frequencies evenly spaced on the ERB scale between the mel LoHz and HiHz.
+// The cochleagram takes the place of the mel filterbank output, with the same log and renormalization,
+// and is convolved with the gabor filters.
+type GammatoneFrontEnd struct {
+	Order   int         `def:"4" desc:"order of the gammatone filters"`
+	BwScale float64     `def:"1.019" desc:"bandwidth of the filters as a multiple of the ERB at the center frequency"`
+	Filters [][]float32 `view:"-" desc:"the power weights of each filter for each dft bin"`
+}
+
+func (gt *GammatoneFrontEnd) Defaults() {
+	gt.Order = 4
+	gt.BwScale = 1.019
+}
+
+func (gt *GammatoneFrontEnd) Type() FrontEndType { return Gammatone }
+
+// ErbRate returns the number of ERBs below the frequency (Glasberg & Moore, 1990)
+func ErbRate(hz float64) float64 {
+	return 21.4 * math.Log10(1+0.00437*hz)
+}
+
+// ErbRateToHz is the inverse of ErbRate
+func ErbRateToHz(erbs float64) float64 {
+	return (math.Pow(10, erbs/21.4) - 1) / 0.00437
+}
+
+func (gt *GammatoneFrontEnd) Init(se *SndEnv) {
+	sr := float64(se.SampleRate())
+	nbins := se.Params.WinSamples/2 + 1
+	nflt := se.Mel.FBank.NFilters
+	lo := ErbRate(float64(se.Mel.FBank.LoHz))
+	hi := ErbRate(float64(se.Mel.FBank.HiHz))
+	gt.Filters = make([][]float32, nflt)
+	for i := range gt.Filters {
+		fc := ErbRateToHz(lo + (hi-lo)*float64(i)/float64(nflt-1))
+		bw := gt.BwScale * 24.7 * (4.37*fc/1000 + 1)
+		w := make([]float32, nbins)
+		for k := range w {
+			f := float64(k) * sr / float64(se.Params.WinSamples)
+			d := (f - fc) / bw
+			w[k] = float32(math.Pow(1+d*d, -float64(gt.Order))) // squared magnitude response, for the power
+		}
+		gt.Filters[i] = w
+	}
+}
+
+func (gt *GammatoneFrontEnd) Step(se *SndEnv, ch, step int) {
+	dftPower(se, ch, step)
+	fb := &se.Mel.FBank
+	for i, w := range gt.Filters {
+		sum := fb.LogOff
+		for k, wt := range w {
+			sum += wt * se.Power.Values[k]
+		}
+		val := fb.LogMin
+		if sum > 0 {
+			val = float32(math.Log(float64(sum)))
+		}
+		if fb.Renorm {
+			val = (val - fb.RenormMin) * fb.RenormScale
+			if val < 0 {
+				val = 0
+			} else if val > 1 {
+				val = 1
+			}
+		}
+		se.MelFBank.Values[i] = val
+		se.MelFBankSegment.Set([]int{step, i, ch}, val)
+	}
+}
+
+func (gt *GammatoneFrontEnd) Output(se *SndEnv, ch int) {
+	gaborOutput(se, ch)
+}
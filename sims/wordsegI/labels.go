@@ -0,0 +1,314 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goki/ki/kit"
+)
+
+// LabelFormat is the format of the label (timing) files in TimesPath
+type LabelFormat int
+
+var KiT_LabelFormat = kit.Enums.AddEnum(LabelFormatN, kit.NotBitFlag, nil)
+
+const (
+	DefaultLabels  LabelFormat = iota // AudacityLabels, or TimitPhnMs for timit sound files
+	AudacityLabels                    // labels exported from Audacity, start and end in seconds, the CV names come from the sequence file
+	TimitPhnMs                        // TIMIT phones with the start and end in milliseconds (.PHN.MS)
+	TimitPhn                          // TIMIT phones as shipped, start and end in samples (.PHN)
+	TimitWrd                          // TIMIT words, start and end in samples (.WRD)
+	TextGrid                          // Praat TextGrid, long or short text format, one interval tier is read
+	ElanTSV                           // ELAN tab-delimited text export, one tier is read
+	LabelFormatN
+)
+
+//go:generate stringer -type=LabelFormat
+
+func (lf LabelFormat) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(lf) }
+func (lf *LabelFormat) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(lf, b) }
+
+// Label is one labelled interval of a sound file
+type Label struct {
+	Name  string  `desc:"the label, e.g. the CV, phone or word -- empty if the format has no names"`
+	Start float64 `desc:"start time in seconds"`
+	End   float64 `desc:"end time in seconds"`
+}
+
+// LabelReader reads the labels of one sound file from a label file of a particular format
+type LabelReader interface {
+	// Ext returns the extension that is added to the sound file name, without .wav, to get the label file name
+	Ext() string
+
+	// Read reads the labels from the file, in order of start time
+	Read(fn string) ([]Label, error)
+
+	// IsSilence returns true if the label marks silence rather than a sound to be predicted
+	IsSilence(name string) bool
+}
+
+// NewLabelReader returns the reader for the format -- tier is the name of the tier for formats
+// with multiple tiers, empty for the first one, and timit says whether DefaultLabels means timit labels
+func NewLabelReader(format LabelFormat, tier string, timit bool) LabelReader {
+	if format == DefaultLabels {
+		format = AudacityLabels
+		if timit {
+			format = TimitPhnMs
+		}
+	}
+	switch format {
+	case TimitPhnMs:
+		return &TimitReader{Extension: ".PHN.MS", Scale: 0.001}
+	case TimitPhn:
+		return &TimitReader{Extension: ".PHN", Scale: 1.0 / 16000}
+	case TimitWrd:
+		return &TimitReader{Extension: ".WRD", Scale: 1.0 / 16000}
+	case TextGrid:
+		return &TextGridReader{Tier: tier}
+	case ElanTSV:
+		return &ElanReader{Tier: tier}
+	}
+	return &AudacityReader{}
+}
+
+// readLines returns the lines of the file
+func readLines(fn string) ([]string, error) {
+	fp, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	var lines []string
+	scanner := bufio.NewScanner(fp)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// Audacity
+
+// AudacityReader reads Audacity label exports, e.g. from "sound finder" - each line is the start and end
+// in seconds and an optional label, and lines starting with '\' hold the frequency range of the line before
+type AudacityReader struct {
+}
+
+func (ar *AudacityReader) Ext() string                { return ".txt" }
+func (ar *AudacityReader) IsSilence(name string) bool { return false }
+
+func (ar *AudacityReader) Read(fn string) ([]Label, error) {
+	lines, err := readLines(fn)
+	if err != nil {
+		return nil, err
+	}
+	var labels []Label
+	for _, t := range lines {
+		if t == "" {
+			break
+		} else if strings.HasPrefix(t, "\\") {
+			continue
+		}
+		flds := strings.Fields(t)
+		if len(flds) < 2 {
+			continue
+		}
+		var l Label
+		l.Start, err = strconv.ParseFloat(flds[0], 64)
+		if err != nil {
+			return labels, err
+		}
+		l.End, err = strconv.ParseFloat(flds[1], 64)
+		if err != nil {
+			return labels, err
+		}
+		if len(flds) > 2 {
+			l.Name = strings.Join(flds[2:], " ")
+		}
+		labels = append(labels, l)
+	}
+	return labels, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// TIMIT
+
+// TimitReader reads the TIMIT phone and word files - each line is the start, end and label,
+// with the times in units of Scale seconds. h# marks the silence at the start and end.
+type TimitReader struct {
+	Extension string  `desc:"file extension, e.g. .PHN or .WRD"`
+	Scale     float64 `desc:"seconds per unit of the times in the file, e.g. 1/16000 for samples"`
+}
+
+func (tr *TimitReader) Ext() string                { return tr.Extension }
+func (tr *TimitReader) IsSilence(name string) bool { return name == "h#" }
+
+func (tr *TimitReader) Read(fn string) ([]Label, error) {
+	lines, err := readLines(fn)
+	if err != nil {
+		return nil, err
+	}
+	var labels []Label
+	for _, t := range lines {
+		flds := strings.Fields(t)
+		if len(flds) < 3 {
+			continue
+		}
+		st, err := strconv.ParseFloat(flds[0], 64)
+		if err != nil {
+			return labels, err
+		}
+		end, err := strconv.ParseFloat(flds[1], 64)
+		if err != nil {
+			return labels, err
+		}
+		labels = append(labels, Label{Name: flds[2], Start: st * tr.Scale, End: end * tr.Scale})
+	}
+	return labels, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// Praat TextGrid
+
+// TextGridReader reads one interval tier of a Praat TextGrid, in either the long or the short text format.
+// Empty intervals are silence.
+type TextGridReader struct {
+	Tier string `desc:"name of the tier to read, empty for the first interval tier"`
+}
+
+func (tg *TextGridReader) Ext() string                { return ".TextGrid" }
+func (tg *TextGridReader) IsSilence(name string) bool { return name == "" }
+
+// textGridValues returns the values of a TextGrid file in order - the long format is the short format
+// with a key before each value, so the values of both are the same
+func textGridValues(lines []string) []string {
+	var vals []string
+	for _, t := range lines {
+		t = strings.TrimSpace(t)
+		if strings.HasSuffix(t, "<exists>") { // tiers? <exists>
+			t = "<exists>"
+		} else if !strings.HasPrefix(t, "\"") {
+			if i := strings.Index(t, "="); i >= 0 {
+				t = strings.TrimSpace(t[i+1:])
+			} else if _, err := strconv.ParseFloat(t, 64); err != nil {
+				continue // e.g. item [1]:
+			}
+		}
+		if strings.HasPrefix(t, "\"") {
+			t = strings.TrimSuffix(strings.TrimPrefix(t, "\""), "\"")
+			t = strings.Replace(t, "\"\"", "\"", -1)
+		}
+		vals = append(vals, t)
+	}
+	return vals
+}
+
+func (tg *TextGridReader) Read(fn string) ([]Label, error) {
+	lines, err := readLines(fn)
+	if err != nil {
+		return nil, err
+	}
+	vals := textGridValues(lines)
+	if len(vals) < 6 || vals[1] != "TextGrid" {
+		return nil, fmt.Errorf("TextGridReader: %v is not a TextGrid text file", fn)
+	}
+	i := 4 // file type, object class, xmin, xmax
+	if vals[i] == "<exists>" {
+		i++
+	}
+	ntiers, err := strconv.Atoi(vals[i])
+	if err != nil {
+		return nil, fmt.Errorf("TextGridReader: %v bad number of tiers", fn)
+	}
+	i++
+	num := func(j int) float64 {
+		if j >= len(vals) {
+			return 0
+		}
+		f, _ := strconv.ParseFloat(vals[j], 64)
+		return f
+	}
+	for t := 0; t < ntiers && i+4 < len(vals); t++ {
+		class := vals[i]
+		name := vals[i+1]
+		n := int(num(i + 4))
+		i += 5
+		if class == "IntervalTier" && (tg.Tier == "" || tg.Tier == name) {
+			var labels []Label
+			for k := 0; k < n && i+2 < len(vals); k++ {
+				labels = append(labels, Label{Name: strings.TrimSpace(vals[i+2]), Start: num(i), End: num(i + 1)})
+				i += 3
+			}
+			return labels, nil
+		}
+		if class == "IntervalTier" {
+			i += 3 * n
+		} else {
+			i += 2 * n // TextTier points are a time and a mark
+		}
+	}
+	return nil, fmt.Errorf("TextGridReader: %v has no interval tier named %q", fn, tg.Tier)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// ELAN
+
+// ElanReader reads one tier of the tab-delimited text exported by ELAN - each line is the tier name,
+// optionally the participant, the begin and end times, optionally the duration, and the annotation last.
+// Times can be in seconds or hh:mm:ss.ms.
+type ElanReader struct {
+	Tier string `desc:"name of the tier to read, empty for all of the lines"`
+}
+
+func (er *ElanReader) Ext() string                { return ".txt" }
+func (er *ElanReader) IsSilence(name string) bool { return name == "" }
+
+// elanTime parses a time in seconds or hh:mm:ss.ms
+func elanTime(s string) (float64, error) {
+	parts := strings.Split(s, ":")
+	secs := 0.0
+	for _, p := range parts {
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0, err
+		}
+		secs = secs*60 + f
+	}
+	return secs, nil
+}
+
+func (er *ElanReader) Read(fn string) ([]Label, error) {
+	lines, err := readLines(fn)
+	if err != nil {
+		return nil, err
+	}
+	var labels []Label
+	for _, t := range lines {
+		flds := strings.Split(t, "\t")
+		if len(flds) < 4 || (er.Tier != "" && flds[0] != er.Tier) {
+			continue
+		}
+		var times []float64
+		for _, f := range flds[1 : len(flds)-1] {
+			if tm, err := elanTime(strings.TrimSpace(f)); err == nil {
+				times = append(times, tm)
+			}
+		}
+		if len(times) < 2 {
+			return labels, errors.New("ElanReader: line without begin and end times: " + t)
+		}
+		labels = append(labels, Label{Name: strings.TrimSpace(flds[len(flds)-1]), Start: times[0], End: times[1]})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Start < labels[j].Start })
+	return labels, nil
+}
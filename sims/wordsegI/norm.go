@@ -0,0 +1,214 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/goki/ki/kit"
+)
+
+// NormType is the normalization of the log filterbank output of each band
+type NormType int
+
+var KiT_NormType = kit.Enums.AddEnum(NormTypeN, kit.NotBitFlag, nil)
+
+const (
+	FixedRenorm NormType = iota // the fixed RenormMin, RenormMax range of the filterbank params
+	UttCMVN                     // mean and variance normalization of each band over the whole sound
+	RunningNorm                 // causal running mean and variance normalization of each band, with time constant TauMs
+	BandAGC                     // automatic gain control of each band, relative to a fast attack, slow release envelope of the band
+	NormTypeN
+)
+
+//go:generate stringer -type=NormType
+
+func (nt NormType) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(nt) }
+func (nt *NormType) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(nt, b) }
+
+// Normalize is the normalization of the log filterbank output in place of the fixed renormalization range,
+// so that the input does not depend on the level of the recording, e.g. between synthesized voices and
+// recorded speakers. The log filterbank output of every step of the sound is computed once, before the
+// first segment is processed, for the statistics. The mfcc front end is not normalized.
+type Normalize struct {
+	Type      NormType `desc:"the normalization of the filterbank output"`
+	ZMin      float32  `def:"-1" desc:"standard deviations from the mean that map to 0 -- for UttCMVN and RunningNorm"`
+	ZMax      float32  `def:"3" desc:"standard deviations from the mean that map to 1 -- for UttCMVN and RunningNorm"`
+	VarMin    float32  `def:"0.01" desc:"minimum variance of a band, so that bands that hardly vary, e.g. in silence, are not amplified"`
+	TauMs     float32  `def:"500" desc:"time constant of the running mean and variance, in milliseconds -- for RunningNorm"`
+	AttackMs  float32  `def:"10" desc:"time constant of the rise of the band envelope, in milliseconds -- for BandAGC"`
+	ReleaseMs float32  `def:"300" desc:"time constant of the decay of the band envelope, in milliseconds -- for BandAGC"`
+	AgcRange  float32  `def:"5" desc:"range of the log filterbank output below the band envelope that maps to 0..1 -- for BandAGC"`
+}
+
+// Defaults sets the default params -- the type is set by the env
+func (nm *Normalize) Defaults() {
+	nm.ZMin = -1
+	nm.ZMax = 3
+	nm.VarMin = 0.01
+	nm.TauMs = 500
+	nm.AttackMs = 10
+	nm.ReleaseMs = 300
+	nm.AgcRange = 5
+}
+
+// NormStats are the offset and scale that map the log filterbank output of each band of each frame of the
+// sound to 0..1 -- frame j is the window at sample Start + j * StepSamples
+type NormStats struct {
+	Frames int         `desc:"number of frames, 1 if the stats are the same for the whole sound, 0 if not computed yet"`
+	Off    [][]float32 `desc:"the offset of each channel, frame * bands + band"`
+	Scale  [][]float32 `desc:"the scale of each channel, frame * bands + band"`
+}
+
+// expRate returns the rate of an exponential average with time constant tauMs for steps of stepMs
+func expRate(stepMs, tauMs float32) float32 {
+	if tauMs <= stepMs {
+		return 1
+	}
+	return stepMs / tauMs
+}
+
+// ComputeNorm computes the NormStats of the sound from the log filterbank output of every step of the signal,
+// at the step spacing of the segments
+func (se *SndEnv) ComputeNorm() {
+	nm := &se.Norm
+	ns := &se.NormStats
+	nb := se.Mel.FBank.NFilters
+	nch := se.Channels()
+	n := se.SignalLen()
+	win := se.Params.WinSamples
+	stp := se.Params.StepSamples
+	nfr := 0
+	if n-se.Start >= win {
+		nfr = (n-se.Start-win)/stp + 1
+	}
+	ns.Frames = 1
+	if nm.Type != UttCMVN && nfr > 0 {
+		ns.Frames = nfr
+	}
+	ns.Off = make([][]float32, nch)
+	ns.Scale = make([][]float32, nch)
+	for ch := 0; ch < nch; ch++ {
+		off := make([]float32, ns.Frames*nb)
+		scl := make([]float32, ns.Frames*nb)
+		ns.Off[ch] = off
+		ns.Scale[ch] = scl
+		if nfr == 0 { // too short to have a frame, use the fixed range
+			for b := 0; b < nb; b++ {
+				off[b] = se.Mel.FBank.RenormMin
+				scl[b] = se.Mel.FBank.RenormScale
+			}
+			continue
+		}
+		sig := se.Signal.Values[ch*n : (ch+1)*n]
+		x := make([]float32, nfr*nb)
+		se.FirstStep = true
+		for j := 0; j < nfr; j++ {
+			st := se.Start + j*stp
+			se.Window.Values = sig[st : st+win]
+			se.FE.Step(se, ch, 0)
+			se.FirstStep = false
+			for b := 0; b < nb; b++ {
+				x[j*nb+b] = se.MelFBankSegment.Value([]int{0, b, ch})
+			}
+		}
+		zrng := nm.ZMax - nm.ZMin
+		switch nm.Type {
+		case UttCMVN:
+			for b := 0; b < nb; b++ {
+				mean := float32(0)
+				for j := 0; j < nfr; j++ {
+					mean += x[j*nb+b]
+				}
+				mean /= float32(nfr)
+				vr := float32(0)
+				for j := 0; j < nfr; j++ {
+					d := x[j*nb+b] - mean
+					vr += d * d
+				}
+				vr /= float32(nfr)
+				if vr < nm.VarMin {
+					vr = nm.VarMin
+				}
+				sd := float32(math.Sqrt(float64(vr)))
+				off[b] = mean + nm.ZMin*sd
+				scl[b] = 1 / (sd * zrng)
+			}
+		case RunningNorm:
+			dt := expRate(se.Params.StepMs, nm.TauMs)
+			for b := 0; b < nb; b++ {
+				mean := x[b]
+				vr := float32(1) // unit variance to start, until there is some history
+				for j := 0; j < nfr; j++ {
+					d := x[j*nb+b] - mean
+					mean += dt * d
+					vr += dt * (d*d - vr)
+					v := vr
+					if v < nm.VarMin {
+						v = nm.VarMin
+					}
+					sd := float32(math.Sqrt(float64(v)))
+					off[j*nb+b] = mean + nm.ZMin*sd
+					scl[j*nb+b] = 1 / (sd * zrng)
+				}
+			}
+		case BandAGC:
+			att := expRate(se.Params.StepMs, nm.AttackMs)
+			rel := expRate(se.Params.StepMs, nm.ReleaseMs)
+			for b := 0; b < nb; b++ {
+				env := x[b]
+				for j := 0; j < nfr; j++ {
+					v := x[j*nb+b]
+					if v > env {
+						env += att * (v - env)
+					} else {
+						env += rel * (v - env)
+					}
+					off[j*nb+b] = env - nm.AgcRange
+					scl[j*nb+b] = 1 / nm.AgcRange
+				}
+			}
+		}
+	}
+	se.FirstStep = true
+}
+
+// NormOn returns true if the filterbank output is normalized rather than renormalized to the fixed range
+func (se *SndEnv) NormOn() bool {
+	return se.Norm.Type != FixedRenorm && se.FrontEnd != Mfcc
+}
+
+// ApplyNorm normalizes the log filterbank output of the steps of the segment in MelFBankSegment with the
+// stats of the frame of each step, which ProcessSegment computes before the steps -- the steps before the
+// start of the sound use the first frame
+func (se *SndEnv) ApplyNorm() {
+	if !se.NormOn() {
+		return
+	}
+	ns := &se.NormStats
+	nb := se.Mel.FBank.NFilters
+	for ch := 0; ch < se.Channels(); ch++ {
+		for s := 0; s < se.Params.SegmentStepsTotal; s++ {
+			j := 0
+			pos := se.Start + se.Segment*se.Params.StrideSamples + se.Params.Steps[s] // sample position of the window, as in SndToWindow
+			if ns.Frames > 1 && pos > se.Start {
+				j = (pos - se.Start) / se.Params.StepSamples // frame j starts at Start + j * StepSamples
+				if j >= ns.Frames {
+					j = ns.Frames - 1
+				}
+			}
+			for b := 0; b < nb; b++ {
+				i := j*nb + b
+				v := (se.MelFBankSegment.Value([]int{s, b, ch}) - ns.Off[ch][i]) * ns.Scale[ch][i]
+				if v < 0 {
+					v = 0
+				} else if v > 1 {
+					v = 1
+				}
+				se.MelFBankSegment.Set([]int{s, b, ch}, v)
+			}
+		}
+	}
+}
@@ -215,4 +215,44 @@ var ParamSets = params.Sets{
 				}},
 		},
 	}},
+	{Name: "SharpA1", Desc: "stronger gabor kwta inhibition of the short pathway, with less A1 pool inhibition to match -- an example of a front end variant along with the network", Sheets: params.Sheets{
+		"Network": &params.Sheet{
+			{Sel: ".A1", Desc: "sparser input needs less inhibition",
+				Params: params.Params{
+					"Layer.Inhib.Pool.Gi": "1.6",
+				}},
+		},
+		"TrainEnv": &params.Sheet{
+			{Sel: "WEEnv", Desc: "less random silence before each sound",
+				Params: params.Params{
+					"WEEnv.SilenceMax": "15",
+				}},
+			{Sel: "#SoundShort", Desc: "sparser gabor output",
+				Params: params.Params{
+					"SndEnv.Kwta.PoolFFFB.Gi": "2.3", // default 2.0
+				}},
+		},
+		"TestEnv": &params.Sheet{
+			{Sel: "#SoundShort", Desc: "same front end as training",
+				Params: params.Params{
+					"SndEnv.Kwta.PoolFFFB.Gi": "2.3", // default 2.0
+				}},
+		},
+	}},
+	{Name: "NeighInhib", Desc: "neighborhood inhibition of the gabor output of every pathway, reducing the redundancy of the input code", Sheets: params.Sheets{
+		"TrainEnv": &params.Sheet{
+			{Sel: "SndEnv", Desc: "inhibition from the same filter in the orthogonal neighbor pools",
+				Params: params.Params{
+					"SndEnv.NeighInhib.On": "true",
+					"SndEnv.NeighInhib.Gi": "0.6",
+				}},
+		},
+		"TestEnv": &params.Sheet{
+			{Sel: "SndEnv", Desc: "same front end as training",
+				Params: params.Params{
+					"SndEnv.NeighInhib.On": "true",
+					"SndEnv.NeighInhib.Gi": "0.6",
+				}},
+		},
+	}},
 }
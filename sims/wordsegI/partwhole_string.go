@@ -16,12 +16,13 @@ func _() {
 	_ = x[NotPartNorWhole-0]
 	_ = x[PartWord-1]
 	_ = x[WholeWord-2]
-	_ = x[PartWholeN-3]
+	_ = x[NonWord-3]
+	_ = x[PartWholeN-4]
 }
 
-const _PartWhole_name = "NotPartNorWholePartWordWholeWordPartWholeN"
+const _PartWhole_name = "NotPartNorWholePartWordWholeWordNonWordPartWholeN"
 
-var _PartWhole_index = [...]uint8{0, 15, 23, 32, 42}
+var _PartWhole_index = [...]uint8{0, 15, 23, 32, 39, 49}
 
 func (i PartWhole) String() string {
 	if i < 0 || i >= PartWhole(len(_PartWhole_index)-1) {
@@ -0,0 +1,161 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/emer/auditory/agabor"
+	"github.com/goki/gi/gi"
+)
+
+// GaborDef is the size and spacing of the gabor filters of a pathway, the other gabor params are the defaults
+type GaborDef struct {
+	TimeSize        int     `desc:"size of the filter in the time (horizontal) domain, in steps"`
+	TimeStride      int     `desc:"spacing in the time domain, in steps"`
+	FreqSize        int     `desc:"size of the filter in the frequency domain, in filterbank bands"`
+	FreqStride      int     `desc:"spacing in the frequency domain"`
+	WaveLen         float32 `desc:"wavelength of the sine waves in normalized units"`
+	HorizSigmaWidth float32 `desc:"gaussian sigma for the horizontal dimension of the horizontal narrow-band filters"`
+}
+
+// Params returns the gabor params, the defaults with the sizes and spacing of the def
+func (gd *GaborDef) Params() agabor.Params {
+	var g agabor.Params
+	g.Defaults()
+	g.TimeSize = gd.TimeSize
+	g.TimeStride = gd.TimeStride
+	g.FreqSize = gd.FreqSize
+	g.FreqStride = gd.FreqStride
+	g.WaveLen = gd.WaveLen
+	g.HorizSigmaWidth = gd.HorizSigmaWidth
+	return g
+}
+
+// Pathway defines one auditory pathway - a window onto the sound at one timescale, with its own
+// processing and gabor filters, whose output is applied to an input layer of the network, which is
+// made for the pathway (see WordNet.SetInputs).
+// Every pathway processes the same sound and steps through it in sync with the others.
+type Pathway struct {
+	Name        string   `desc:"name of the pathway, e.g. SoundShort"`
+	Desc        string   `desc:"description of the pathway"`
+	Layer       string   `desc:"the input layer the output is applied to, e.g. A1 -- this is the element name of the env State, with _1 added for the second ear"`
+	Belt        string   `desc:"the belt layer the input layer projects to and drives the pulvinar of: CB for the caudal stream, whose input layers have class A1 and also project to RB, or RB for the rostral stream, whose input layers have class R"`
+	Late        bool     `desc:"apply the input at the start of the first cycle rather than before the alpha cycle is initialized"`
+	SegmentMs   float32  `desc:"length of the segment's worth of input in milliseconds"`
+	WinMs       float32  `desc:"input window in milliseconds"`
+	StepMs      float32  `desc:"milliseconds the window is stepped along for each step of the segment"`
+	StrideMs    float32  `desc:"milliseconds the segment moves on each trial -- must be the same for all of the pathways to keep them in sync"`
+	BorderSteps int      `desc:"steps of overlap with the previous and next segment, which the gabor filters look over"`
+	PoolsY      int      `desc:"number of pools of the input layer along the frequency axis"`
+	PoolsX      int      `desc:"number of pools of the input layer along the time axis"`
+	RenormMin   float32  `desc:"minimum of the renormalization of the filterbank output"`
+	RenormMax   float32  `desc:"maximum of the renormalization of the filterbank output"`
+	LoHz        float32  `desc:"low frequency end of the filterbank"`
+	HiHz        float32  `desc:"high frequency end of the filterbank"`
+	Gabor       GaborDef `desc:"the gabor filters"`
+	Flux        bool     `desc:"add the spectral flux -- the onsets and offsets of the filterbank bands -- as a feature column of each pool, see Features"`
+	Envelope    bool     `desc:"add the broadband envelope and its change as a feature column of each pool, see Features"`
+	FeatLayer   string   `desc:"input layer of the feature columns -- empty to add them after the gabor filter columns of Layer, which is made wide enough"`
+}
+
+// Config sets the params of the sound env from the pathway, after the sound env Defaults
+func (pw *Pathway) Config(se *SndEnv) {
+	se.Nm = pw.Name
+	se.Dsc = pw.Desc
+
+	// these should match the number of neuron pools in the input layer
+	se.GborPoolsY = pw.PoolsY
+	se.GborPoolsX = pw.PoolsX
+
+	se.Params.SegmentMs = pw.SegmentMs
+	se.Params.WinMs = pw.WinMs
+	se.Params.StepMs = pw.StepMs
+	se.Params.StrideMs = pw.StrideMs
+	se.Params.BorderSteps = pw.BorderSteps
+
+	// these overrides must follow Mel.Defaults
+	se.Mel.FBank.RenormMin = pw.RenormMin
+	se.Mel.FBank.RenormMax = pw.RenormMax
+	se.Mel.FBank.LoHz = pw.LoHz
+	se.Mel.FBank.HiHz = pw.HiHz
+
+	se.Feats.Flux = pw.Flux
+	se.Feats.Envelope = pw.Envelope
+}
+
+// FeatCols returns the number of feature columns of the pathway
+func (pw *Pathway) FeatCols() int {
+	ft := Features{Flux: pw.Flux, Envelope: pw.Envelope}
+	return ft.Cols()
+}
+
+// Pathways is the list of auditory pathways of the model, typically loaded from a JSON file
+type Pathways []*Pathway
+
+// DefaultPathways returns the short and long duration pathways, applied to A1 and R
+func DefaultPathways() Pathways {
+	return Pathways{
+		{Name: "SoundShort", Desc: "150 ms window onto sound", Layer: "A1", Belt: "CB",
+			SegmentMs: 150, WinMs: 25, StepMs: 10, StrideMs: 100, BorderSteps: 5, PoolsY: 12, PoolsX: 6,
+			RenormMin: 2, RenormMax: 9, LoHz: 20, HiHz: 6000, // 2/9 better than 0/10
+			// with Stride/StepMs equal to 10 and 5 border steps on either side there will be 25 values for the gabor stepping to cover
+			Gabor: GaborDef{TimeSize: 10, TimeStride: 3, FreqSize: 6, FreqStride: 3, WaveLen: 6, HorizSigmaWidth: 0.2}},
+		{Name: "SoundLong", Desc: "200 ms window onto sound", Layer: "R", Belt: "RB", Late: true,
+			SegmentMs: 150, WinMs: 25, StepMs: 10, StrideMs: 100, BorderSteps: 6, PoolsY: 12, PoolsX: 6,
+			RenormMin: 2, RenormMax: 9, LoHz: 20, HiHz: 6000,
+			// for a time size of 10 the border steps needs to go to 7, etc.
+			Gabor: GaborDef{TimeSize: 6, TimeStride: 4, FreqSize: 6, FreqStride: 3, WaveLen: 6, HorizSigmaWidth: 0.2}},
+	}
+}
+
+// ByLayerTry returns the index of the pathway applied to the layer, and an error if none
+func (ps *Pathways) ByLayerTry(layer string) (int, error) {
+	for i, pw := range *ps {
+		if pw.Layer == layer {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("Pathways: no pathway for layer %v", layer)
+}
+
+// ByFeatLayer returns the index of the pathway whose feature columns are applied to the layer
+// of their own, and -1 if none
+func (ps *Pathways) ByFeatLayer(layer string) int {
+	for i, pw := range *ps {
+		if pw.FeatLayer != "" && pw.FeatLayer == layer && pw.FeatCols() > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// OpenJSON opens pathways from a JSON-formatted file.
+func (ps *Pathways) OpenJSON(filename gi.FileName) error {
+	*ps = make(Pathways, 0) // reset
+	b, err := ioutil.ReadFile(string(filename))
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return json.Unmarshal(b, ps)
+}
+
+// SaveJSON saves pathways to a JSON-formatted file.
+func (ps *Pathways) SaveJSON(filename gi.FileName) error {
+	b, err := json.MarshalIndent(ps, "", "  ")
+	if err != nil {
+		log.Println(err) // unlikely
+		return err
+	}
+	err = ioutil.WriteFile(string(filename), b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
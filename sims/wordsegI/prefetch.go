@@ -0,0 +1,101 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// Prefetch is the next sound of an env, drawn on the main goroutine, in order, and loaded and processed
+// in the background into a copy of the env while the network runs the current sound
+type Prefetch struct {
+	Stop bool       `desc:"the draw reached the end of the sound list, there is no next sound"`
+	Idx  int        `desc:"index into SndFiles of the next sound"`
+	Env  *WEEnv     `desc:"copy of the env the next sound is loaded into"`
+	Done chan error `desc:"receives the error of loading the sound when it is loaded and processed"`
+}
+
+// CanPrefetch returns true if the next sound can be loaded in the background -- the carried samples of
+// continuous sounds are only known once the current sound is done, and rendering can draw random numbers
+func (we *WEEnv) CanPrefetch() bool {
+	return we.Prefetch && !we.Continuous && !we.IsRendered()
+}
+
+// StartPrefetch draws the next sound, its silence and its augmentation, and starts loading and processing it
+// in the background, into the spare pathways. The draws are made here, in order, from the random sources of the
+// env, so the order of the sounds is the same as without prefetching -- the copy shares the random sources, so
+// the background load must not draw from them.
+func (we *WEEnv) StartPrefetch() {
+	if !we.CanPrefetch() {
+		return
+	}
+	pf := &Prefetch{}
+	cur := we.SndIdx
+	pf.Stop = we.NextSndFile()
+	pf.Idx = we.SndIdx
+	we.SndIdx = cur
+	we.pf = pf
+	if pf.Stop || pf.Idx == -1 {
+		return
+	}
+
+	nw := *we
+	nw.pf = nil
+	nw.Snds = we.spare
+	for i, se := range nw.Snds {
+		se.Params = we.Snds[i].Params
+	}
+	nw.SndCur = we.SndFiles[pf.Idx]
+	nw.msSilence = we.DrawSilence()
+	if nw.Augment.On { // drawn into the copy, the current sound keeps its draw
+		nw.Augment.Draw()
+	}
+	nw.CVTimes = nil
+	pf.Env = &nw
+	pf.Done = make(chan error, 1)
+	go func() {
+		err := nw.LoadSndFile()
+		if err == nil {
+			for _, se := range nw.Snds {
+				se.Precompute()
+			}
+		}
+		pf.Done <- err
+	}()
+}
+
+// AdoptPrefetch waits for the prefetched sound and makes it the current sound, swapping the pathways it
+// was loaded into with the current pathways, which become the spares for the next prefetch -- the values
+// are swapped so that anything viewing a pathway of the env sees the current sound
+func (we *WEEnv) AdoptPrefetch() (done bool, err error) {
+	pf := we.pf
+	we.pf = nil
+	we.SndIdx = pf.Idx
+	if pf.Stop {
+		return true, nil
+	}
+	if pf.Idx == -1 { // no sound or we exhausted the sounds - done
+		return true, nil
+	}
+	err = <-pf.Done
+	if err != nil { // the spares are partly loaded -- keep the current sound
+		return false, err
+	}
+	nw := pf.Env
+	for i, se := range we.Snds {
+		*se, *we.spare[i] = *we.spare[i], *se
+	}
+	we.SndCur = nw.SndCur
+	we.SeqCur = nw.SeqCur
+	we.TrialName = nw.TrialName
+	we.CVTimes = nw.CVTimes
+	we.msSilence = nw.msSilence
+	we.Augment = nw.Augment
+	return false, err
+}
+
+// StopPrefetch waits for any sound being loaded in the background and drops it, e.g. when the sound list changes
+func (we *WEEnv) StopPrefetch() {
+	if we.pf != nil && we.pf.Done != nil {
+		<-we.pf.Done // the spare pathways must not be written to after this
+	}
+	we.pf = nil
+}
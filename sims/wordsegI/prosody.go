@@ -0,0 +1,168 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/goki/ki/kit"
+)
+
+// CueCond is how the prosodic cues of a stimulus set relate to the word boundaries marked by the TPs
+type CueCond int
+
+var KiT_CueCond = kit.Enums.AddEnum(CueCondN, kit.NotBitFlag, nil)
+
+const (
+	NoCue       CueCond = iota // no prosodic cues
+	CueAgree                   // every cue marks the word boundaries, e.g. word initial stress or word final lengthening
+	CueConflict                // every cue falls within the words, e.g. stress on the second syllable (Thiessen & Saffran 2003)
+	CueMixed                   // some cues agree with the TPs and some conflict
+	CueCondN
+)
+
+//go:generate stringer -type=CueCond
+
+func (cc CueCond) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(cc) }
+func (cc *CueCond) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(cc, b) }
+
+// Prosody adds prosodic cues to the syllables of a word stream, after the sound is loaded or rendered,
+// using the CV times to find the syllables. The stressed syllable position gets an amplitude boost,
+// a pitch raise and lengthening, and a pause can follow another syllable position. Stress on the first
+// syllable, lengthening of the last and pauses after the last agree with the word boundaries of the TPs,
+// any other position conflicts with them.
+type Prosody struct {
+	StressPos  int     `desc:"syllable position within the word of the stressed syllable, 0 is word initial, -1 for no stress"`
+	GainDb     float64 `desc:"amplitude boost in decibels of the stressed syllable"`
+	PitchSemi  float64 `desc:"pitch raise in semitones of the stressed syllable"`
+	Lengthen   float64 `desc:"proportion by which the stressed syllable is lengthened, e.g. 0.3 is 1.3 times as long -- with no gain or pitch change this is word final lengthening when StressPos is the last position"`
+	PauseMs    float64 `desc:"milliseconds of silence inserted after the syllables in position PausePos, 0 for no pauses"`
+	PausePos   int     `desc:"syllable position after which the pauses are inserted, the last position of the word puts the pauses between words"`
+	CVsPerWord int     `view:"-" desc:"how many CVs per word - set from the stimulus set"`
+}
+
+// IsStress returns true if the prosody changes the stressed syllable
+func (pr *Prosody) IsStress() bool {
+	return pr.StressPos >= 0 && (pr.GainDb != 0 || pr.PitchSemi != 0 || pr.Lengthen != 0)
+}
+
+// Cond returns how the cues relate to the word boundaries of the TPs
+func (pr *Prosody) Cond() CueCond {
+	last := pr.CVsPerWord - 1
+	agree := 0
+	conflict := 0
+	if pr.StressPos >= 0 {
+		// stress marks the start of a word and lengthening the end, each is a cue of its own
+		if pr.GainDb != 0 || pr.PitchSemi != 0 {
+			if pr.StressPos == 0 {
+				agree++
+			} else {
+				conflict++
+			}
+		}
+		if pr.Lengthen != 0 {
+			if pr.StressPos == last {
+				agree++
+			} else {
+				conflict++
+			}
+		}
+	}
+	if pr.PauseMs > 0 {
+		if pr.PausePos == last {
+			agree++
+		} else {
+			conflict++
+		}
+	}
+	switch {
+	case agree > 0 && conflict > 0:
+		return CueMixed
+	case agree > 0:
+		return CueAgree
+	case conflict > 0:
+		return CueConflict
+	}
+	return NoCue
+}
+
+// Apply adds the cues to the signal, one channel of sr samples per second, and returns the new signal
+// and CV times. The times are the start and end of each CV in seconds and the syllable position of a CV
+// is its order in the sequence, not counting silence, modulo CVsPerWord. Anything between the end of a CV
+// and the start of the next is copied unchanged after the CV and its pause, and is not part of the new CV time.
+func (pr *Prosody) Apply(sig []float32, sr int, times []CVTime) ([]float32, []CVTime) {
+	if pr.CVsPerWord <= 0 || len(times) == 0 {
+		return sig, times
+	}
+	out := make([]float32, 0, len(sig))
+	nt := make([]CVTime, len(times))
+	copy(nt, times)
+	toSample := func(t float64) int {
+		s := int(math.Round(t * float64(sr)))
+		if s < 0 {
+			return 0
+		}
+		if s > len(sig) {
+			return len(sig)
+		}
+		return s
+	}
+	st := toSample(times[0].Start)
+	out = append(out, sig[:st]...)
+	ord := 0
+	for i, cvt := range times {
+		end := len(sig)
+		if i < len(times)-1 {
+			end = toSample(times[i+1].Start)
+		}
+		if end < st {
+			end = st
+		}
+		syl := append([]float32{}, sig[st:end]...)
+		nxt := toSample(cvt.End) - st // the part of the segment after the CV is copied as is
+		if nxt > len(syl) {
+			nxt = len(syl)
+		}
+		if nxt < 0 {
+			nxt = 0
+		}
+		cv := syl[:nxt]
+		gap := syl[nxt:]
+		pos := -1
+		if cvt.Name != "ss" {
+			pos = ord % pr.CVsPerWord
+			ord++
+		}
+		if pos >= 0 && pos == pr.StressPos && pr.IsStress() {
+			cv = pr.Stress(cv, sr)
+		}
+		nt[i].Start = float64(len(out)) / float64(sr)
+		out = append(out, cv...)
+		nt[i].End = float64(len(out)) / float64(sr)
+		if pos >= 0 && pos == pr.PausePos && pr.PauseMs > 0 {
+			out = append(out, make([]float32, int(pr.PauseMs*float64(sr)/1000))...)
+		}
+		out = append(out, gap...)
+		st = end
+	}
+	return out, nt
+}
+
+// Stress returns the syllable with the amplitude boost, pitch raise and lengthening of stress
+func (pr *Prosody) Stress(syl []float32, sr int) []float32 {
+	if len(syl) == 0 {
+		return syl
+	}
+	ratio := math.Pow(2, pr.PitchSemi/12)
+	if ratio != 1 {
+		syl = Resample(syl, ratio) // raises the pitch and shortens by ratio, stretched back below
+	}
+	syl = TimeStretch(syl, ratio*(1+pr.Lengthen), sr)
+	g := float32(math.Pow(10, pr.GainDb/20))
+	for i := range syl {
+		syl[i] *= g
+	}
+	return syl
+}
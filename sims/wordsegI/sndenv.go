@@ -7,6 +7,7 @@ import (
 	"github.com/emer/auditory/dft"
 	"github.com/emer/auditory/mel"
 	"github.com/emer/auditory/sound"
+	"github.com/emer/emergent/params"
 	"github.com/emer/etable/etensor"
 	"github.com/emer/leabra/fffb"
 	"github.com/emer/vision/kwta"
@@ -22,7 +23,9 @@ type Params struct {
 	StrideMs    float32 `def:"100" desc:"how far to move on each trial"`
 	BorderSteps int     `def:"6" view:"+" desc:"overlap with previous segment"`
 	Channel     int     `viewif:"Channels=1" desc:"specific channel to process, if input has multiple channels, and we only process one of them (-1 = process all)"`
+	Mix         bool    `desc:"mix (average) the channels of a multi-channel sound into one channel, rather than selecting Channel"`
 	PadValue    float32 `desc:"value to use of signal when padding"`
+	SampleRate  int     `def:"0" desc:"canonical sample rate -- every sound is resampled to this rate when loaded so the window, step and filter geometry is the same for all corpora, 0 to keep the rate of each sound -- set by the env, Defaults does not reset it"`
 
 	// these are calculated
 	WinSamples        int   `inactive:"+" desc:"number of samples to process each step"`
@@ -39,10 +42,10 @@ func (se *SndEnv) ParamDefaults() {
 	se.Params.WinMs = 25.0
 	se.Params.StepMs = 5.0
 	se.Params.SegmentMs = 100.0
-	se.Params.Channel = 0
 	se.Params.PadValue = 0.0
 	se.Params.StrideMs = 100.0
 	se.Params.BorderSteps = 6 // should be a multiple if SegmentMs is greater than StrideMs
+	// SampleRate, Channel and Mix are not reset here - they are needed when the sound is loaded, before Init calls Defaults
 }
 
 type SndEnv struct {
@@ -75,10 +78,22 @@ type SndEnv struct {
 	GborKwta        etensor.Float32   `view:"no-inline" desc:" post-kwta output of full segment's worth of gabor steps"`
 	Inhibs          fffb.Inhibs       `view:"no-inline" desc:"inhibition values for A1 KWTA"`
 	ExtGi           etensor.Float32   `view:"no-inline" desc:"A1 simple extra Gi from neighbor inhibition tensor"`
-	NeighInhib      kwta.NeighInhib   `desc:"neighborhood inhibition for V1s -- each unit gets inhibition from same feature in nearest orthogonal neighbors -- reduces redundancy of feature code"`
+	NeighInhib      kwta.NeighInhib   `desc:"neighborhood inhibition of the gabor output -- each unit gets inhibition from the same filter in the nearest pools orthogonal to the filter orientation, through ExtGi -- reduces redundancy of feature code"`
 	Kwta            kwta.KWTA         `desc:"kwta parameters, using FFFB form"`
 	FftCoefs        []complex128      `view:"-" desc:" discrete fourier transform (fft) output complex representation"`
 	Fft             *fourier.CmplxFFT `view:"-" desc:" struct for fast fourier transform"`
+	FrontEnd        FrontEndType      `desc:"the auditory preprocessing that computes the output for the input layers -- mel filterbank and gabor filters, MFCCs, or gammatone cochleagram and gabor filters"`
+	FE              FrontEnd          `view:"-" desc:"the front end of type FrontEnd, kept across sounds"`
+	Continuous      bool              `desc:"the signal continues the previous signal -- Lead is prepended in place of silence, the end is not padded and SegCnt only counts the segments that fit in the signal"`
+	Lead            [][]float32       `view:"-" desc:"samples of each channel from the end of the previous signal, see Carry, that are prepended to the signal when Continuous"`
+	Start           int               `view:"-" desc:"sample position of the first segment -- the samples of Lead before Start are only looked back on by the first segments"`
+	Cache           SegCache          `view:"-" desc:"the cached segments of the current sound, see FeatCache"`
+	Norm            Normalize         `desc:"normalization of the log filterbank output, in place of the fixed renormalization range -- the type is set by the env"`
+	NormStats       NormStats         `view:"-" desc:"the normalization stats of the current sound, computed before its first segment"`
+	Feats           Features          `desc:"optional feature planes, the spectral flux and the broadband envelope, added to the input -- which ones are set by the pathway"`
+	FeatOutput      etensor.Float32   `view:"no-inline" desc:" the feature planes of the segment, [chan, freq, time, 2, feature]"`
+	InputCat        etensor.Float32   `view:"-" desc:" the output of a channel with the feature columns added, see InputWithFeats"`
+	Sheets          []*params.Sheet   `view:"-" desc:"param sheets of the env, applied to the params of each sound after their defaults, see WEEnv.SetParams"`
 
 	// internal state - view:"-"
 	FirstStep bool `view:"-" desc:" if first frame to process -- turns off prv smoothing of dft power"`
@@ -88,6 +103,8 @@ type SndEnv struct {
 func (se *SndEnv) Defaults() {
 	se.FirstStep = true
 	se.ParamDefaults()
+	se.Feats.Defaults()
+	se.Norm.Defaults()
 	se.Mel.Defaults() // calls melfbank defaults
 }
 
@@ -96,12 +113,25 @@ func (se *SndEnv) Defaults() {
 // Can also pass milliseconds of silence to prepend to start of signal if you want some random amount of silence
 // at start for variability
 func (se *SndEnv) Init(gp agabor.Params, msSilenceAdd, msSilenceRmStart, msSilenceRmEnd float64) (err error, segments int) {
-	sr := se.Sound.SampleRate()
+	sr := se.SampleRate()
 	if sr <= 0 {
 		fmt.Println("sample rate <= 0")
 		err = errors.New("sample rate <= 0")
 		return err, 0
 	}
+
+	// the gabor, kwta and filterbank params are reset for each sound, so the env param sheets are applied after
+	se.Gbor = gp
+	se.NeighInhib.Defaults()
+	se.NeighInhib.On = false // off unless turned on by the param sheets, e.g. "SndEnv.NeighInhib.On"
+	se.Kwta.Defaults()
+	se.Mel.FBank.NFilters = 43
+	se.ApplySheets()
+	se.NormStats = NormStats{}
+	if se.NormOn() {
+		se.Mel.FBank.Renorm = false // the log values are normalized after each segment, see ApplyNorm
+	}
+
 	se.Params.WinSamples = MSecToSamples(se.Params.WinMs, sr)
 	se.Params.StepSamples = MSecToSamples(se.Params.StepMs, sr)
 	se.Params.SegmentSamples = MSecToSamples(se.Params.SegmentMs, sr)
@@ -110,45 +140,64 @@ func (se *SndEnv) Init(gp agabor.Params, msSilenceAdd, msSilenceRmStart, msSilen
 	se.Params.StrideSamples = MSecToSamples(se.Params.StrideMs, sr)
 
 	if msSilenceRmStart >= 0 && msSilenceRmEnd > msSilenceRmStart {
-		st := MSecToSamples(float32(msSilenceRmStart), se.Sound.SampleRate())
-		end := MSecToSamples(float32(msSilenceRmEnd), se.Sound.SampleRate())
-		tmp := make([]float32, end-st)
-		copy(tmp, se.Signal.Values[st:end])
-		se.Signal.Values = make([]float32, len(tmp))
-		copy(se.Signal.Values, tmp)
+		st := MSecToSamples(float32(msSilenceRmStart), sr)
+		end := MSecToSamples(float32(msSilenceRmEnd), sr)
+		se.MapChannels(func(sig []float32) []float32 {
+			return append([]float32{}, sig[st:end]...)
+		})
 	}
 
-	n := int((msSilenceAdd * float64(se.Params.StrideSamples)) / 100.0)
-	silence := make([]float32, n)
-	se.Signal.Values = append(silence, se.Signal.Values...)
-	se.Signal.Values = se.Pad(se.Signal.Values)
+	if se.Continuous {
+		if len(se.Lead) == 0 {
+			se.Start = 0
+		}
+		ch := 0
+		se.MapChannels(func(sig []float32) []float32 {
+			var lead []float32
+			if len(se.Lead) > 0 {
+				c := ch
+				if c >= len(se.Lead) { // a sound with more channels than the last continues its last channel
+					c = len(se.Lead) - 1
+				}
+				lead = se.Lead[c]
+			}
+			ch++
+			return append(append([]float32{}, lead...), sig...)
+		})
+	} else {
+		se.Start = 0
+		n := int((msSilenceAdd * float64(se.Params.StrideSamples)) / 100.0)
+		se.MapChannels(func(sig []float32) []float32 {
+			return se.Pad(append(make([]float32, n), sig...))
+		})
+	}
+	nch := se.Channels()
 
-	se.Gbor.On = true
 	if se.Gbor.On {
-		se.Gbor.Defaults()
-		se.Gbor = gp
-		se.NeighInhib.Defaults() // NeighInhib code not working yet - need to pass 4d tensor not 5d
-
 		//se.Gbor.WaveLen = 4 * float32(se.Gbor.TimeSize/8)
 		se.GborFilters.SetShape([]int{se.Gbor.NFilters, se.Gbor.FreqSize, se.Gbor.TimeSize}, nil, nil)
 		se.Gbor.RenderFilters(&se.GborFilters)
-		se.Kwta.Defaults()
-		se.GborOutput.SetShape([]int{se.Sound.Channels(), se.GborPoolsY, se.GborPoolsX, 2, se.Gbor.NFilters}, nil, []string{"chan", "freq", "time"})
+		se.GborOutput.SetShape([]int{nch, se.GborPoolsY, se.GborPoolsX, 2, se.Gbor.NFilters}, nil, []string{"chan", "freq", "time"})
 		se.GborOutput.SetMetaData("odd-row", "true")
 		se.GborOutput.SetMetaData("grid-fill", ".9")
 		se.GborKwta.CopyShapeFrom(&se.GborOutput)
 		se.GborKwta.CopyMetaData(&se.GborOutput)
+		se.FeatOutput.SetShape([]int{nch, se.GborPoolsY, se.GborPoolsX, 2, se.Feats.Cols()}, nil, []string{"chan", "freq", "time"})
+		se.FeatOutput.CopyMetaData(&se.GborOutput)
 		se.ExtGi.SetShape([]int{se.GborPoolsY, se.GborPoolsX, 2, se.Gbor.NFilters}, nil, nil) // passed in for each channel
 	}
 
-	se.Mel.FBank.NFilters = 43
+	if se.FE == nil || se.FE.Type() != se.FrontEnd {
+		se.FE = NewFrontEnd(se.FrontEnd)
+	}
+	se.FE.Init(se)
 	winSamplesHalf := se.Params.WinSamples/2 + 1
 	se.Dft.Initialize(se.Params.WinSamples)
-	se.Mel.InitFilters(se.Params.WinSamples, se.Sound.SampleRate(), &se.MelFilters) // call after non-default values are set!
+	se.Mel.InitFilters(se.Params.WinSamples, sr, &se.MelFilters) // call after non-default values are set!
 	se.Window.SetShape([]int{se.Params.WinSamples}, nil, nil)
 	se.Power.SetShape([]int{winSamplesHalf}, nil, nil)
 	se.LogPower.CopyShapeFrom(&se.Power)
-	se.PowerSegment.SetShape([]int{se.Params.SegmentStepsTotal, winSamplesHalf, se.Sound.Channels()}, nil, nil)
+	se.PowerSegment.SetShape([]int{se.Params.SegmentStepsTotal, winSamplesHalf, nch}, nil, nil)
 	if se.Dft.CompLogPow {
 		se.LogPowerSegment.CopyShapeFrom(&se.PowerSegment)
 	}
@@ -172,83 +221,291 @@ func (se *SndEnv) Init(gp agabor.Params, msSilenceAdd, msSilenceRmStart, msSilen
 	}
 
 	se.MelFBank.SetShape([]int{se.Mel.FBank.NFilters}, nil, nil)
-	se.MelFBankSegment.SetShape([]int{se.Params.SegmentStepsTotal, se.Mel.FBank.NFilters, se.Sound.Channels()}, nil, nil)
+	se.MelFBankSegment.SetShape([]int{se.Params.SegmentStepsTotal, se.Mel.FBank.NFilters, nch}, nil, nil)
 	if se.Mel.CompMfcc {
 		se.MfccDctSegment.CopyShapeFrom(&se.MelFBankSegment)
 		se.MfccDct.SetShape([]int{se.Mel.FBank.NFilters}, nil, nil)
 	}
 
-	siglen := len(se.Signal.Values) - se.Params.SegmentSamples*se.Sound.Channels()
-	siglen = siglen / se.Sound.Channels()
-	se.SegCnt = siglen/se.Params.StrideSamples + 1 // add back the first segment subtracted at from siglen calculation
+	if se.Continuous { // only the segments whose last window is all signal, the rest is carried to the next signal
+		siglen := se.SignalLen() - se.Start - (se.Params.Steps[se.Params.SegmentStepsTotal-1] + se.Params.WinSamples)
+		se.SegCnt = 0
+		if siglen >= 0 {
+			se.SegCnt = siglen/se.Params.StrideSamples + 1
+		}
+	} else {
+		siglen := se.SignalLen() - se.Params.SegmentSamples
+		se.SegCnt = siglen/se.Params.StrideSamples + 1 // add back the first segment subtracted at from siglen calculation
+	}
 	se.Segment = -1
 	return nil, se.SegCnt
 }
 
-// LoadSound
+// Carry sets Lead and Start to the samples of each channel of the signal not yet processed after segs segments,
+// along with the samples before them that the next segment looks back on, so the next signal
+// of a continuous stream starts where this one left off.
+func (se *SndEnv) Carry(segs int) {
+	pos := se.Start + segs*se.Params.StrideSamples
+	se.Lead = nil
+	se.Start = 0
+	n := se.SignalLen()
+	if len(se.Params.Steps) == 0 || pos > n {
+		return
+	}
+	st := pos + se.Params.Steps[0] // Steps[0] is the furthest look back
+	if st < 0 {
+		st = 0
+	}
+	for ch := 0; ch < se.Channels(); ch++ {
+		se.Lead = append(se.Lead, append([]float32{}, se.Signal.Values[ch*n+st:(ch+1)*n]...))
+	}
+	se.Start = pos - st
+}
+
+// LoadSound copies the sound into the signal, resampled to the canonical sample rate. A multi-channel sound
+// is mixed to one channel if Mix, else Channel is selected, or all channels are kept if Channel is -1
 func (se *SndEnv) LoadSound() bool {
-	if se.Sound.Channels() > 1 {
+	nch := se.Sound.Channels()
+	switch {
+	case nch == 1:
+		se.Sound.SoundToTensor(&se.Signal, 0)
+	case se.Params.Mix:
 		se.Sound.SoundToTensor(&se.Signal, -1)
-	} else {
+		se.MixChannels()
+	case se.Params.Channel >= 0 && se.Params.Channel < nch:
 		se.Sound.SoundToTensor(&se.Signal, se.Params.Channel)
+	case se.Params.Channel >= nch:
+		fmt.Printf("LoadSound: channel %d of a sound with %d channels, using channel 0\n", se.Params.Channel, nch)
+		se.Sound.SoundToTensor(&se.Signal, 0)
+	default:
+		se.Sound.SoundToTensor(&se.Signal, -1)
+	}
+	se.ResampleSignal()
+	return true
+}
+
+// ShareSound sets the sound to the sound already loaded by from, copying the signal rather than converting
+// and resampling the sound again if both load it the same way
+func (se *SndEnv) ShareSound(from *SndEnv) bool {
+	se.Sound = from.Sound
+	if se.Params.SampleRate != from.Params.SampleRate || se.Params.Channel != from.Params.Channel || se.Params.Mix != from.Params.Mix {
+		return se.LoadSound()
 	}
+	se.Signal.CopyShapeFrom(&from.Signal)
+	copy(se.Signal.Values, from.Signal.Values)
 	return true
 }
 
-// ApplyKwta runs the kwta algorithm on the raw activations
+// Channels returns the number of channels of the signal, which can be fewer than the sound if a channel
+// is selected or the channels are mixed
+func (se *SndEnv) Channels() int {
+	if se.Signal.NumDims() == 2 {
+		return se.Signal.Dim(0)
+	}
+	return 1
+}
+
+// SignalLen returns the number of samples in each channel of the signal
+func (se *SndEnv) SignalLen() int {
+	return se.Signal.Len() / se.Channels()
+}
+
+// MapChannels sets each channel of the signal to the result of f on the channel,
+// which must return the same length for every channel
+func (se *SndEnv) MapChannels(f func(sig []float32) []float32) {
+	nch := se.Channels()
+	n := se.SignalLen()
+	var chans [][]float32
+	for c := 0; c < nch; c++ {
+		chans = append(chans, f(se.Signal.Values[c*n:(c+1)*n]))
+	}
+	m := len(chans[0])
+	if nch == 1 {
+		se.Signal.SetShape([]int{m}, nil, nil)
+	} else {
+		se.Signal.SetShape([]int{nch, m}, nil, nil)
+	}
+	for c, ch := range chans {
+		copy(se.Signal.Values[c*m:], ch)
+	}
+}
+
+// MixChannels averages the channels of the signal into one channel
+func (se *SndEnv) MixChannels() {
+	nch := se.Channels()
+	if nch == 1 {
+		return
+	}
+	n := se.SignalLen()
+	mix := make([]float32, n)
+	for c := 0; c < nch; c++ {
+		for i, v := range se.Signal.Values[c*n : (c+1)*n] {
+			mix[i] += v / float32(nch)
+		}
+	}
+	se.Signal.SetShape([]int{n}, nil, nil)
+	copy(se.Signal.Values, mix)
+}
+
+// SampleRate returns the sample rate of the signal - Params.SampleRate, or the rate of the sound if that is 0
+func (se *SndEnv) SampleRate() int {
+	if se.Params.SampleRate > 0 {
+		return se.Params.SampleRate
+	}
+	return se.Sound.SampleRate()
+}
+
+// ResampleSignal resamples each channel of the signal from the rate of the sound to the canonical rate.
+// Call after setting the signal from the sound, which keeps its own rate.
+func (se *SndEnv) ResampleSignal() {
+	from := se.Sound.SampleRate()
+	to := se.SampleRate()
+	if from <= 0 || from == to {
+		return
+	}
+	se.MapChannels(func(sig []float32) []float32 {
+		return ResampleRate(sig, from, to)
+	})
+}
+
+// NeighOrient returns the orientation of gabor filter f, as an index into kwta.Neigh4X and Neigh4Y --
+// the horizontal narrow-band filters come first, followed by the filters at successive 45 degree angles
+func (se *SndEnv) NeighOrient(f int) int {
+	if f < se.Gbor.NHoriz {
+		return 0
+	}
+	return (f - se.Gbor.NHoriz + 1) % 4
+}
+
+// ApplyNeighInhib computes ExtGi for the kwta of channel ch -- the inhibition of each unit is the max of
+// the same filter and polarity in the two nearest pools orthogonal to the filter orientation, i.e. above and
+// below in frequency for the horizontal filters and before and after in time for the vertical filter, times NeighInhib.Gi.
+// The outputs of the mfcc front end are not oriented and get no neighborhood inhibition.
+func (se *SndEnv) ApplyNeighInhib(ch int) {
+	if !se.NeighInhib.On || se.FrontEnd == Mfcc {
+		se.ExtGi.SetZeros()
+		return
+	}
+	act := se.GborOutput.SubSpace([]int{ch}).(*etensor.Float32)
+	ny := act.Dim(0)
+	nx := act.Dim(1)
+	npol := act.Dim(2)
+	nf := act.Dim(3)
+	idx := func(y, x, pol, f int) int {
+		return ((y*nx+x)*npol+pol)*nf + f
+	}
+	gis := se.ExtGi.Values
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			for pol := 0; pol < npol; pol++ {
+				for f := 0; f < nf; f++ {
+					ang := se.NeighOrient(f)
+					gi := float32(0)
+					for _, dir := range []int{1, -1} {
+						py := y + dir*kwta.Neigh4Y[ang]
+						px := x + dir*kwta.Neigh4X[ang]
+						if py < 0 || py >= ny || px < 0 || px >= nx {
+							continue
+						}
+						if ngi := se.NeighInhib.Gi * act.Values[idx(py, px, pol, f)]; ngi > gi {
+							gi = ngi
+						}
+					}
+					gis[idx(y, x, pol, f)] = gi
+				}
+			}
+		}
+	}
+}
+
+// ApplyKwta runs the kwta algorithm on the raw activations of the channel
 func (se *SndEnv) ApplyKwta(ch int) {
-	se.GborKwta.CopyFrom(&se.GborOutput)
+	rawSS := se.GborOutput.SubSpace([]int{ch}).(*etensor.Float32)
+	kwtaSS := se.GborKwta.SubSpace([]int{ch}).(*etensor.Float32)
 	if se.Kwta.On {
-		rawSS := se.GborOutput.SubSpace([]int{ch}).(*etensor.Float32)
-		kwtaSS := se.GborKwta.SubSpace([]int{ch}).(*etensor.Float32)
 		se.Kwta.KWTAPool(rawSS, kwtaSS, &se.Inhibs, &se.ExtGi)
+	} else {
+		kwtaSS.CopyFrom(rawSS)
+	}
+}
+
+// Output returns the gabor output of the channel, after kwta if on - a channel past the last, e.g. the
+// second ear of a mono sound, gets the last channel
+func (se *SndEnv) Output(ch int) *etensor.Float32 {
+	if ch >= se.Channels() {
+		ch = se.Channels() - 1
+	}
+	if se.Kwta.On {
+		return se.GborKwta.SubSpace([]int{ch}).(*etensor.Float32)
 	}
+	return se.GborOutput.SubSpace([]int{ch}).(*etensor.Float32)
 }
 
-// ProcessSegment processes the entire segment's input by processing a small overlapping set of samples on each pass
+// ProcessSegment processes the entire segment's input by processing a small overlapping set of samples on each pass,
+// or copies the output of the segment from the cache if the sound is cached
 func (se *SndEnv) ProcessSegment() (moreSegments bool) {
 	//start := time.Now()
 	moreSegments = true
-	se.Power.SetZeros()
-	se.LogPower.SetZeros()
-	se.PowerSegment.SetZeros()
-	se.LogPowerSegment.SetZeros()
-	se.MelFBankSegment.SetZeros()
-	se.MfccDctSegment.SetZeros()
 	//moreSamples := true
 	se.Segment++
 	//fmt.Printf("Segment: %d\n", se.Segment)
-	for ch := int(0); ch < se.Sound.Channels(); ch++ {
-		for s := 0; s < int(se.Params.SegmentStepsTotal); s++ {
-			err := se.ProcessStep(ch, s)
-			if err != nil {
-				break
+	if !se.Cache.Segment(se, se.Segment) {
+		if se.NormOn() && se.NormStats.Frames == 0 {
+			se.ComputeNorm() // uses the first step of the segment tensors, before they are cleared
+		}
+		se.Power.SetZeros()
+		se.LogPower.SetZeros()
+		se.PowerSegment.SetZeros()
+		se.LogPowerSegment.SetZeros()
+		se.MelFBankSegment.SetZeros()
+		se.MfccDctSegment.SetZeros()
+		for ch := int(0); ch < se.Channels(); ch++ {
+			for s := 0; s < int(se.Params.SegmentStepsTotal); s++ {
+				err := se.ProcessStep(ch, s)
+				if err != nil {
+					break
+				}
 			}
 		}
+		se.ApplyNorm()
+		se.ApplyFrontEnd()
+		se.ApplyFeatures()
+		se.Cache.Add(se)
 	}
-	remaining := len(se.Signal.Values) - (se.Segment+1)*se.Params.StrideSamples
+	remaining := se.SignalLen() - (se.Segment+1)*se.Params.StrideSamples
 	//fmt.Printf("total length = %v, remaining = %v\n", len(se.Signal.Values), remaining)
 	if remaining < se.Params.SegmentSamples {
 		moreSegments = false
 		//fmt.Printf("Last Segment for %v: %d\n", se.SndFileCur, se.Segment)
 	}
-	se.ApplyGabor()
 	//se.ToolBar.UpdateActions()
 	//elapsed := time.Since(start)
 	//log.Printf("ProcessSegment took %s", elapsed)
 	return moreSegments
 }
 
+// Precompute processes all of the segments of the sound after Init, keeping them in the cache
+// so ProcessSegment only copies them -- used to process the next sound in the background
+func (se *SndEnv) Precompute() {
+	if se.Cache.Hit {
+		return
+	}
+	se.Cache.Memory = true
+	for se.Segment+1 < se.SegCnt {
+		se.ProcessSegment()
+	}
+	se.Cache.Hit = len(se.Cache.Output) == se.SegCnt
+	se.Segment = -1
+}
+
 // ProcessStep processes a step worth of sound input from current input_pos, and increment input_pos by input.step_samples
-// Process the data by doing a fourier transform and computing the power spectrum, then apply mel filters to get the frequency
-// bands that mimic the non-linear human perception of sound
+// The front end processes the data, e.g. by doing a fourier transform and computing the power spectrum, then applying mel
+// filters to get the frequency bands that mimic the non-linear human perception of sound
 func (se *SndEnv) ProcessStep(ch int, step int) error {
 	offset := se.Params.Steps[step]
 	err := se.SndToWindow(offset, ch)
 	if err == nil {
-		se.Fft.Reset(se.Params.WinSamples)
-		se.Dft.Filter(int(ch), int(step), &se.Window, se.FirstStep, se.Params.WinSamples, se.FftCoefs, se.Fft, &se.Power, &se.LogPower, &se.PowerSegment, &se.LogPowerSegment)
-		se.Mel.Filter(int(ch), int(step), &se.Window, &se.MelFilters, &se.Power, &se.MelFBankSegment, &se.MelFBank, &se.MfccDctSegment, &se.MfccDct)
+		se.FE.Step(se, ch, step)
 		se.FirstStep = false
 	}
 	return err
@@ -256,41 +513,34 @@ func (se *SndEnv) ProcessStep(ch int, step int) error {
 
 // SndToWindow gets sound from the signal (i.e. the slice of input values) at given position and channel, into Window
 func (se *SndEnv) SndToWindow(stepOffset int, ch int) error {
-	if se.Signal.NumDims() == 1 {
-		start := se.Segment*int(se.Params.StrideSamples) + stepOffset // segments start at zero
-		end := start + se.Params.WinSamples
-		if end > len(se.Signal.Values) {
-			return errors.New("SndToWindow: end beyond signal length!!")
-		}
-		var pad []float32
-		if start < 0 && end <= 0 {
-			pad = make([]float32, end-start)
-			se.Window.Values = pad[0:]
-		} else if start < 0 && end > 0 {
-			pad = make([]float32, 0-start)
-			se.Window.Values = pad[0:]
-			se.Window.Values = append(se.Window.Values, se.Signal.Values[0:end]...)
-		} else {
-			se.Window.Values = se.Signal.Values[start:end]
-		}
+	n := se.SignalLen()
+	// the channels of a multi-channel signal follow one another
+	sig := se.Signal.Values[ch*n : (ch+1)*n]
+	start := se.Start + se.Segment*int(se.Params.StrideSamples) + stepOffset // segments start at zero, or Start for a continuous signal
+	end := start + se.Params.WinSamples
+	if end > len(sig) {
+		return errors.New("SndToWindow: end beyond signal length!!")
+	}
+	var pad []float32
+	if start < 0 && end <= 0 {
+		pad = make([]float32, end-start)
+		se.Window.Values = pad[0:]
+	} else if start < 0 && end > 0 {
+		pad = make([]float32, 0-start)
+		se.Window.Values = pad[0:]
+		se.Window.Values = append(se.Window.Values, sig[0:end]...)
 	} else {
-		// ToDo: implement
-		fmt.Printf("SndToWindow: else case not implemented - please report this issue")
+		se.Window.Values = sig[start:end]
 	}
 	return nil
 }
 
-// ApplyGabor convolves the gabor filters with the mel output
-func (se *SndEnv) ApplyGabor() (tsr *etensor.Float32) {
+// ApplyFrontEnd computes the front end output of the segment for each channel, e.g. convolves the gabor filters with the mel output
+func (se *SndEnv) ApplyFrontEnd() (tsr *etensor.Float32) {
 	if se.Gbor.On {
-		for ch := int(0); ch < se.Sound.Channels(); ch++ {
-			agabor.Conv(ch, se.Gbor, se.Params.SegmentSteps, se.Params.BorderSteps, &se.GborOutput, se.Mel.FBank.NFilters, &se.GborFilters, &se.MelFBankSegment)
-			//if se.NeighInhib.On {
-			//	se.NeighInhib.Inhib4(&se.GborOutput, &se.ExtGi)
-			//} else {
-			//	se.ExtGi.SetZeros()
-			//}
-
+		for ch := int(0); ch < se.Channels(); ch++ {
+			se.FE.Output(se, ch)
+			se.ApplyNeighInhib(ch)
 			if se.Kwta.On {
 				se.ApplyKwta(ch)
 				tsr = &se.GborKwta
@@ -305,6 +555,18 @@ func (se *SndEnv) ApplyGabor() (tsr *etensor.Float32) {
 func (se *SndEnv) Name() string { return se.Nm }
 func (se *SndEnv) Desc() string { return se.Dsc }
 
+// TypeName and Class, along with Name, let the Sel of a param sheet select a pathway, e.g. #SoundShort
+func (se *SndEnv) TypeName() string { return "SndEnv" }
+func (se *SndEnv) Class() string    { return "" }
+
+// ApplySheets applies the param sheets of the env, e.g. "SndEnv.Gbor.TimeStride"
+func (se *SndEnv) ApplySheets() {
+	for _, sh := range se.Sheets {
+		sh.Apply(se, false)
+	}
+	se.Kwta.Update() // the values derived from the kwta params
+}
+
 // Tail returns the number of samples that remain beyond the last full stride
 func (se *SndEnv) Tail(signal []float32) int {
 	temp := len(signal) - se.Params.SegmentSamples
@@ -333,3 +595,81 @@ func MSecToSamples(ms float32, rate int) int {
 func SamplesToMSec(samples int, rate int) float32 {
 	return 1000.0 * float32(samples) / float32(rate)
 }
+
+// ResampleZeros is the number of zero crossings on each side of the sinc kernel of ResampleRate
+const ResampleZeros = 16
+
+// ResamplePhases is the maximum number of fractional input positions at which ResampleRate tabulates its kernel --
+// rates whose reduced ratio has more output phases than this use the nearest tabulated position
+const ResamplePhases = 1024
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// ResampleRate returns the signal resampled from one sample rate to another by band limited interpolation -
+// a sinc kernel with a Blackman window, whose cutoff is the lower of the two Nyquist frequencies
+// so that downsampling does not alias. The output samples fall at a repeating set of fractional
+// positions of the input (the phases), so the kernel is computed once for each phase rather than for each sample.
+func ResampleRate(sig []float32, from, to int) []float32 {
+	if from == to || len(sig) == 0 {
+		return sig
+	}
+	g := gcd(from, to)
+	up := to / g // output sample i is at input position i * down / up
+	down := from / g
+	ratio := float64(to) / float64(from)
+	cut := math.Min(1, ratio) * 0.95 // cutoff as a proportion of the input Nyquist, a little below to leave room for the transition band
+	half := float64(ResampleZeros) / cut
+	w := int(half)
+	ntap := 2*w + 2 // inputs floor(t) - w .. floor(t) + w + 1 cover t +/- half
+	nph := up
+	if nph > ResamplePhases {
+		nph = ResamplePhases
+	}
+	kern := make([]float32, nph*ntap)
+	for p := 0; p < nph; p++ {
+		f := float64(p) / float64(nph)
+		for m := 0; m < ntap; m++ {
+			x := f + float64(w-m) // distance of input floor(t) - w + m from t
+			if math.Abs(x) > half {
+				continue
+			}
+			wn := 0.42 + 0.5*math.Cos(math.Pi*x/half) + 0.08*math.Cos(2*math.Pi*x/half)
+			sinc := 1.0
+			if x != 0 {
+				sinc = math.Sin(math.Pi*cut*x) / (math.Pi * cut * x)
+			}
+			kern[p*ntap+m] = float32(cut * sinc * wn)
+		}
+	}
+	out := make([]float32, int(float64(len(sig))*ratio))
+	for i := range out {
+		pos := i * down
+		k := pos / up
+		p := pos % up
+		if nph != up {
+			p = int(float64(p)*float64(nph)/float64(up) + 0.5)
+			if p == nph {
+				p = 0
+				k++
+			}
+		}
+		kp := kern[p*ntap : (p+1)*ntap]
+		lo := k - w
+		sum := float32(0)
+		for m, c := range kp {
+			j := lo + m
+			if j < 0 || j >= len(sig) {
+				continue
+			}
+			sum += sig[j] * c
+		}
+		out[i] = sum
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/ccnlab/statlearn/synth"
+	"github.com/goki/gi/gi"
+	"github.com/goki/ki/kit"
+)
+
+// StimSet describes one set of stimuli - where the sound, sequence and timing files live
+// and the information needed to decode the CVs of the sequences.
+// The -trnlist, -tstlist, -prelist and -pretstlist args name a StimSet in the stimulus sets file.
+type StimSet struct {
+	Name           string      `desc:"name of the stimulus set - this is the name passed as -trnlist, -tstlist, etc"`
+	Desc           string      `desc:"description of the stimulus set, e.g. the experiment it simulates"`
+	SndPath        string      `desc:"base path to all sound, sequence and timing files"`
+	SeqsPath       string      `desc:"path to the human readable files of the sound sequences, relative to SndPath"`
+	WavsPath       string      `desc:"path to wav files, relative to SndPath"`
+	TimesPath      string      `desc:"path to the timing information for wav files (labels), relative to SndPath"`
+	TimesFormat    LabelFormat `desc:"format of the label files in TimesPath, e.g. AudacityLabels, TimitPhn, TextGrid or ElanTSV -- DefaultLabels is Audacity labels, or TIMIT .PHN.MS files if Timit"`
+	TimesTier      string      `desc:"name of the tier of the label files to read, for formats with multiple tiers (TextGrid, ElanTSV) -- empty for the first"`
+	Words          bool        `desc:"load word labels alongside the phones to mark the word boundaries of natural speech, for the between vs within word stats -- e.g. TIMIT .WRD files or a word tier"`
+	WordsFormat    LabelFormat `desc:"format of the word label files in TimesPath -- DefaultLabels is TIMIT .WRD files if Timit, otherwise TimesFormat"`
+	WordsTier      string      `desc:"name of the word tier of the label files, for formats with multiple tiers (TextGrid, ElanTSV), e.g. words"`
+	SndList        string      `desc:"file with the list of sound files, relative to SndPath"`
+	TestSndList    string      `desc:"file with the list of sound files to test on, relative to SndPath, used instead of SndList when the set is named as the test or pretest list -- empty to test on SndList"`
+	Timit          bool        `desc:"are the sound files timit files"`
+	Channel        int         `desc:"channel of multi-channel (e.g. stereo) sound files to use, -1 for all of the channels -- with -binaural the first two go to separate input layers"`
+	Mix            bool        `desc:"mix the channels of multi-channel sound files into one, rather than selecting Channel"`
+	CVs            []string    `desc:"the full list of CVs, grouped by syllable position - order is important!"`
+	CVsPerWord     int         `desc:"how many CVs per word"`
+	CVsPerPos      int         `desc:"how many CV possibilities per syllable position"`
+	CVsByPos       []int       `desc:"how many CV possibilities in each syllable position, for languages where the positions differ - empty means CVsPerPos for every position"`
+	Silence        bool        `desc:"add random period of silence at start of sequence"`
+	Continuous     bool        `desc:"the sound files are one continuous stream, e.g. an infant familiarization stream split into files -- the files are joined with no silence and the CV state is not reset between them"`
+	DepDist        int         `desc:"distance of the nonadjacent dependencies, e.g. 2 for AxC frames, 0 if none"`
+	DepFrames      []string    `desc:"the nonadjacent frames of the language, the first and dependent CV separated by a space, e.g. 'pe rud'"`
+	TestType       TestType    `desc:"which variety of test item is in this set - only used for test sets"`
+	TestWordsPart  []string    `desc:"the 'part words' to collect stats on, CVs separated by spaces - only the first two CVs are compared - empty means all part words"`
+	TestWordsWhole []string    `desc:"the 'whole words' to collect stats on, CVs separated by spaces - only the first two CVs are compared - empty means all whole words"`
+	TestWordsNon   []string    `desc:"the 'non words' to collect stats on, CVs separated by spaces - only the first two CVs are compared - empty means all non words"`
+
+	ToneLang *ToneLang    `desc:"if set, the sequences are of pure tones rendered from this definition rather than loaded from wav files -- SndList then lists the sequence names and CVs are the tone names"`
+	Synth    *synth.Synth `desc:"if set, the sequences are rendered by the formant synthesizer with these parameters rather than loaded from wav files -- SndList then lists the sequence names, or if empty all the files in SeqsPath are used"`
+	Prosody  *Prosody     `desc:"if set, these prosodic cues are added to the syllables of the sequences, e.g. stress or lengthening of one syllable position or pauses between words -- the cues can agree or conflict with the TPs"`
+}
+
+// StimSets is a collection of stimulus sets, typically loaded from a JSON file
+type StimSets []*StimSet
+
+// ByNameTry returns the stimulus set of given name, and an error if not found
+func (ss *StimSets) ByNameTry(name string) (*StimSet, error) {
+	for _, st := range *ss {
+		if st.Name == name {
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("StimSets: stimulus set named %v not found", name)
+}
+
+// OpenJSON opens stimulus sets from a JSON-formatted file.
+func (ss *StimSets) OpenJSON(filename gi.FileName) error {
+	*ss = make(StimSets, 0) // reset
+	b, err := ioutil.ReadFile(string(filename))
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return json.Unmarshal(b, ss)
+}
+
+// SaveJSON saves stimulus sets to a JSON-formatted file.
+func (ss *StimSets) SaveJSON(filename gi.FileName) error {
+	b, err := json.MarshalIndent(ss, "", "  ")
+	if err != nil {
+		log.Println(err) // unlikely
+		return err
+	}
+	err = ioutil.WriteFile(string(filename), b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+func (tt TestType) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(tt) }
+func (tt *TestType) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(tt, b) }
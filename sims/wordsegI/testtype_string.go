@@ -16,12 +16,13 @@ func _() {
 	_ = x[TestingTypeNotSet-0]
 	_ = x[SequenceTesting-1]
 	_ = x[PartWholeTesting-2]
-	_ = x[TestTypeN-3]
+	_ = x[DependencyTesting-3]
+	_ = x[TestTypeN-4]
 }
 
-const _TestType_name = "TestingTypeNotSetSequenceTestingPartWholeTestingTestTypeN"
+const _TestType_name = "TestingTypeNotSetSequenceTestingPartWholeTestingDependencyTestingTestTypeN"
 
-var _TestType_index = [...]uint8{0, 17, 32, 48, 57}
+var _TestType_index = [...]uint8{0, 17, 32, 48, 65, 74}
 
 func (i TestType) String() string {
 	if i < 0 || i >= TestType(len(_TestType_index)-1) {
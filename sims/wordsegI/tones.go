@@ -0,0 +1,102 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"math"
+
+	"github.com/emer/auditory/sound"
+	"github.com/emer/etable/etensor"
+	"github.com/go-audio/audio"
+)
+
+// Tone is one pure tone "syllable" of a tone language, e.g. for the nonlinguistic
+// tone sequence experiments (Saffran et al 1999). The tone is a sine wave with a linear
+// rise and fall of amplitude at its start and end to avoid clicks.
+type Tone struct {
+	Name   string  `desc:"name of the tone, used in the sequence files in place of a CV, e.g. C4 or F#"`
+	Hz     float64 `desc:"frequency of the tone in hertz"`
+	Ms     float64 `desc:"duration of the tone in milliseconds, including rise and fall"`
+	Amp    float64 `def:"0.5" desc:"peak amplitude of the tone, 0 to 1"`
+	RiseMs float64 `def:"10" desc:"milliseconds for the amplitude to ramp up from zero at tone onset"`
+	FallMs float64 `def:"10" desc:"milliseconds for the amplitude to ramp down to zero at tone offset"`
+}
+
+// ToneLang is a language of pure tones - sequences of tone names are rendered directly
+// into the sound signal so no wav or label files are needed
+type ToneLang struct {
+	Tones      []Tone  `desc:"the tones of the language - names must match the CVs of the stimulus set"`
+	GapMs      float64 `desc:"milliseconds of silence between successive tones, 0 for a continuous stream"`
+	SampleRate int     `def:"16000" desc:"sample rate of the rendered signal"`
+}
+
+// ToneByName returns the tone of the given name, and an error if not found
+func (tl *ToneLang) ToneByName(name string) (*Tone, error) {
+	for i := range tl.Tones {
+		if tl.Tones[i].Name == name {
+			return &tl.Tones[i], nil
+		}
+	}
+	return nil, errors.New("ToneLang.ToneByName: tone not found: " + name)
+}
+
+// Wave returns a sound.Wave with no samples but with the format of the rendered signal,
+// which is all SndEnv.Init needs from the sound
+func (tl *ToneLang) Wave() sound.Wave {
+	var w sound.Wave
+	w.Buf = &audio.IntBuffer{Format: &audio.Format{NumChannels: 1, SampleRate: tl.SampleRate}, SourceBitDepth: 16}
+	return w
+}
+
+// Render renders the sequence of tones into sig, a 1D tensor of samples in the range -1 to 1,
+// and returns the start and end time (in seconds) of each tone. Silence ("ss") in the sequence
+// becomes a gap the length of the first tone of the language.
+func (tl *ToneLang) Render(seq []string, sig *etensor.Float32) ([]CVTime, error) {
+	if tl.SampleRate <= 0 {
+		return nil, errors.New("ToneLang.Render: SampleRate must be greater than zero")
+	}
+	sr := float64(tl.SampleRate)
+	gap := int(tl.GapMs * sr / 1000)
+	var vals []float32
+	var times []CVTime
+	for _, nm := range seq {
+		if nm == "" {
+			continue
+		}
+		if len(vals) > 0 {
+			vals = append(vals, make([]float32, gap)...)
+		}
+		st := len(vals)
+		if nm == "ss" {
+			if len(tl.Tones) > 0 {
+				vals = append(vals, make([]float32, int(tl.Tones[0].Ms*sr/1000))...)
+			}
+			times = append(times, CVTime{Name: nm, Start: float64(st) / sr, End: float64(len(vals)) / sr})
+			continue
+		}
+		t, err := tl.ToneByName(nm)
+		if err != nil {
+			return nil, err
+		}
+		n := int(t.Ms * sr / 1000)
+		rise := int(t.RiseMs * sr / 1000)
+		fall := int(t.FallMs * sr / 1000)
+		for i := 0; i < n; i++ {
+			env := 1.0
+			if i < rise {
+				env = float64(i) / float64(rise)
+			}
+			if n-i <= fall {
+				env = math.Min(env, float64(n-i-1)/float64(fall))
+			}
+			vals = append(vals, float32(t.Amp*env*math.Sin(2*math.Pi*t.Hz*float64(i)/sr)))
+		}
+		times = append(times, CVTime{Name: nm, Start: float64(st) / sr, End: float64(len(vals)) / sr})
+	}
+	sig.SetShape([]int{len(vals)}, nil, nil)
+	copy(sig.Values, vals)
+	return times, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// CVPair is a pair of adjacent CVs in a sequence, Last followed by Cur
+type CVPair struct {
+	Last string
+	Cur  string
+}
+
+// TransProbs holds the counts of CV bigrams from a set of sequences (typically the training sequences)
+// from which the forward and backward transitional probabilities and the bigram frequencies are computed.
+// Silence ("ss") is not part of any bigram.
+type TransProbs struct {
+	NBigrams int            `inactive:"+" desc:"total number of bigrams counted"`
+	NSeqs    int            `inactive:"+" desc:"number of sequences the bigrams were counted from"`
+	BiCnts   map[CVPair]int `view:"-" desc:"count of each bigram"`
+	LastCnts map[string]int `view:"-" desc:"count of each CV as the first CV of a bigram"`
+	CurCnts  map[string]int `view:"-" desc:"count of each CV as the second CV of a bigram"`
+}
+
+// Reset clears all of the counts
+func (tp *TransProbs) Reset() {
+	tp.NBigrams = 0
+	tp.NSeqs = 0
+	tp.BiCnts = make(map[CVPair]int)
+	tp.LastCnts = make(map[string]int)
+	tp.CurCnts = make(map[string]int)
+}
+
+// AddSeq counts the bigrams of one sequence of CVs
+func (tp *TransProbs) AddSeq(cvs []string) {
+	if tp.BiCnts == nil {
+		tp.Reset()
+	}
+	last := ""
+	for _, cv := range cvs {
+		if cv == "" || cv == "ss" {
+			continue
+		}
+		if last != "" {
+			tp.BiCnts[CVPair{last, cv}]++
+			tp.LastCnts[last]++
+			tp.CurCnts[cv]++
+			tp.NBigrams++
+		}
+		last = cv
+	}
+	tp.NSeqs++
+}
+
+// Fwd returns the forward transitional probability P(cur | last)
+func (tp *TransProbs) Fwd(last, cur string) float64 {
+	n := tp.LastCnts[last]
+	if n == 0 {
+		return 0
+	}
+	return float64(tp.BiCnts[CVPair{last, cur}]) / float64(n)
+}
+
+// Bwd returns the backward transitional probability P(last | cur)
+func (tp *TransProbs) Bwd(last, cur string) float64 {
+	n := tp.CurCnts[cur]
+	if n == 0 {
+		return 0
+	}
+	return float64(tp.BiCnts[CVPair{last, cur}]) / float64(n)
+}
+
+// Freq returns the frequency of the bigram as a proportion of all bigrams
+func (tp *TransProbs) Freq(last, cur string) float64 {
+	if tp.NBigrams == 0 {
+		return 0
+	}
+	return float64(tp.BiCnts[CVPair{last, cur}]) / float64(tp.NBigrams)
+}
@@ -15,6 +15,7 @@ import (
 	"math/rand"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/emer/emergent/emer"
@@ -37,14 +38,6 @@ import (
 	"github.com/goki/mat32"
 )
 
-// !! really 3 groups of 4, first, second and third position of trisyllabic word - keep the order!!
-var CVs_I = []string{"da", "go", "pa", "ti", "ro", "la", "bi", "bu", "pi", "tu", "ku", "do"}
-var CVs_III = []string{"su", "ro", "pa", "ho", "ba", "lu", "go", "li", "hi", "ra", "di", "sa"}
-var CVs_IV = []string{"do", "na", "hu", "ki", "ka", "to", "mo", "mu", "ru", "si", "ta", "po"}
-var CVs_V = []string{"gu", "ma", "bi", "bu", "ri", "gi", "tu", "ni", "ha", "so", "ga", "bo"}
-var CVs_VI = []string{"da", "ti", "nu", "lo", "ku", "no", "pi", "du", "mi", "pu", "ko", "la"}
-var CVs_MT = []string{"ti", "do", "ga", "mo", "may", "bu", "pi", "ku"}
-
 // TestType
 type TestType int
 
@@ -55,6 +48,7 @@ const (
 	TestingTypeNotSet TestType = iota
 	SequenceTesting            // the test items are like the training sound sequences
 	PartWholeTesting           // the test items are "part words" and "whole words" see saffran 1996/1998 and Graf-Estes 2015
+	DependencyTesting          // the test items are trained and violated nonadjacent (AxC) frames see Gomez 2002 and Newport & Aslin 2004
 	TestTypeN
 )
 
@@ -101,6 +95,10 @@ type Sim struct {
 	TstList         string            `desc:"name of file with list of testing sounds for this run - only used for cmdline runs"`
 	PreTrnList      string            `desc:"name of file with list of pre-training sounds for this run"`
 	PreTstList      string            `desc:"name of file with list of pre-testing sounds for this run"`
+	StimFile        string            `desc:"JSON file describing the stimulus sets that TrnList, TstList, PreTrnList and PreTstList name"`
+	StimSets        StimSets          `view:"no-inline" desc:"the stimulus sets loaded from StimFile"`
+	PathsFile       string            `desc:"JSON file defining the auditory pathways, each a window onto the sound at one timescale applied to an input layer -- empty for the default short and long pathways applied to A1 and R"`
+	Paths           Pathways          `view:"no-inline" desc:"the auditory pathways of all of the envs, from PathsFile"`
 	Tag             string            `desc:"extra tag string to add to any file names output from sim (e.g., weights files, log files, params for run)"`
 	HoldoutID       string            `desc:"unique id to specify the holdout testing file for the job, must specify for each job using holdout testing!"`
 	StartRun        int               `desc:"starting run number -- typically 0 but can be set in command args for parallel runs on a cluster"`
@@ -159,6 +157,11 @@ type Sim struct {
 	EpcInWordCosDiffTRC []float64        `inactive:"+" desc:"last epoch's average cosine difference for within for TRC layers"`
 	EpcPartCosDiffTRC   []float64        `inactive:"+" desc:"last epoch's average cosine difference for 'part words' for TRC layers"`
 	EpcWholeCosDiffTRC  []float64        `inactive:"+" desc:"last epoch's average cosine difference for 'whole words' for TRC layers"`
+	EpcNonCosDiffTRC    []float64        `inactive:"+" desc:"last epoch's average cosine difference for 'non words' for TRC layers"`
+	EpcFrameCosDiffTRC  []float64        `inactive:"+" desc:"last epoch's average cosine difference at the dependent CV of trained nonadjacent frames for TRC layers"`
+	EpcViolCosDiffTRC   []float64        `inactive:"+" desc:"last epoch's average cosine difference at the dependent CV of violated nonadjacent frames for TRC layers"`
+	EpcHiBwdCosDiffTRC  []float64        `inactive:"+" desc:"last epoch's average cosine difference for transitions with backward TP >= BwdTPThr for TRC layers"`
+	EpcLoBwdCosDiffTRC  []float64        `inactive:"+" desc:"last epoch's average cosine difference for transitions with backward TP < BwdTPThr for TRC layers"`
 
 	// intermediary vars for various stats - view:"-"
 	SumCosDiffTRC       []float64 `view:"-" inactive:"+" desc:"sum to increment as we go through epoch, per TRC"`
@@ -166,13 +169,36 @@ type Sim struct {
 	SumInWordCosDiffTRC []float64 `view:"-" inactive:"+" desc:"Within means for trials that fall between words"`
 	SumPartCosDiffTRC   []float64 `view:"-" inactive:"+" desc:"part is for 'part word' cos diff"`
 	SumWholeCosDiffTRC  []float64 `view:"-" inactive:"+" desc:"whole is for 'whole word' cos diff"`
+	SumNonCosDiffTRC    []float64 `view:"-" inactive:"+" desc:"non is for 'non word' cos diff"`
+	SumFrameCosDiffTRC  []float64 `view:"-" inactive:"+" desc:"frame is for the dependent CV of trained nonadjacent frames"`
+	SumViolCosDiffTRC   []float64 `view:"-" inactive:"+" desc:"viol is for the dependent CV of violated nonadjacent frames"`
+	SumHiBwdCosDiffTRC  []float64 `view:"-" inactive:"+" desc:"hi bwd is for transitions with a high backward TP"`
+	SumLoBwdCosDiffTRC  []float64 `view:"-" inactive:"+" desc:"lo bwd is for transitions with a low backward TP"`
 	CntErr              int       `view:"-" inactive:"+" desc:"sum of errs to increment as we go through epoch"`
 	BtwWordCnt          int       `inactive:"+" desc:"number of trials of epoch that end between words"`
 	InWordCnt           int       `inactive:"+" desc:"number of trials of epoch that end within words"`
 	PartWordCnt         int       `inactive:"+" desc:"number of trials of epoch that end between words"`
 	WholeWordCnt        int       `inactive:"+" desc:"number of trials of epoch that end within words"`
+	NonWordCnt          int       `inactive:"+" desc:"number of trials of epoch that are a CV order never heard in training"`
+	FrameCnt            int       `inactive:"+" desc:"number of trials of epoch that complete a trained nonadjacent frame"`
+	ViolCnt             int       `inactive:"+" desc:"number of trials of epoch that violate a trained nonadjacent frame"`
+	HiBwdCnt            int       `inactive:"+" desc:"number of trials of epoch at a transition with a high backward TP"`
+	LoBwdCnt            int       `inactive:"+" desc:"number of trials of epoch at a transition with a low backward TP"`
 	TestWordsPart       []string  `desc:"all the words for testing"`
 	TestWordsWhole      []string  `desc:"the whole words for testing"`
+	TestWordsNon        []string  `desc:"the non words for testing"`
+
+	TPs      TransProbs `view:"no-inline" desc:"CV bigram counts of the training sequences, for the transitional probabilities of each trial"`
+	BwdTPThr float64    `desc:"transitions with a backward TP at or above this are 'high' backward TP for the CalcBwdTP stats"`
+
+	Augment    bool         `desc:"apply random pitch, tempo, level and noise augmentation to each training and pretraining sound -- the ranges are in TrainEnv.Augment and PreTrainEnv.Augment"`
+	AugSeed    int64        `desc:"seed for the augmentation draws, to which the run is added"`
+	AugNoise   NoiseType    `desc:"type of noise the augmentation adds"`
+	Prefetch   bool         `desc:"load and process the next sound of each env in the background while the network runs the current sound"`
+	FeatCache  string       `desc:"directory of the on-disk cache of the processed segments of each sound file, shared by all of the envs -- empty for no cache"`
+	FrontEnd   FrontEndType `desc:"the auditory preprocessing of the sounds for all of the envs - mel filterbank and gabor filters, MFCCs, or gammatone cochleagram and gabor filters"`
+	SampleRate int          `desc:"the sample rate every sound of all of the envs is resampled to when loaded -- 0 keeps the rate of each sound file"`
+	Norm       NormType     `desc:"the normalization of the log filterbank output for all of the envs - the fixed renormalization range, or per-utterance, running or automatic gain control normalization of each band, so the input does not depend on the recording level"`
 
 	// tensors for holding various layer state info
 	LogActsTsr *etensor.Float32 `view:"-" desc:"for holding layer activations for each exemplar"`
@@ -185,19 +211,17 @@ type Sim struct {
 	RndSeeds    []int64 `view:"-" desc:"a list of random seeds to use for each run"`
 
 	// gui
-	Win           *gi.Window         `view:"-" desc:"main GUI window"`
-	NetView       *netview.NetView   `view:"-" desc:"the network viewer"`
-	StructView    *giv.StructView    `view:"-" desc:"the params viewer"`
-	ToolBar       *gi.ToolBar        `view:"-" desc:"the master toolbar"`
-	TrnEpcPlot    *eplot.Plot2D      `view:"-" desc:"the training epoch plot"`
-	TrnTrlPlot    *eplot.Plot2D      `view:"-" desc:"the training trial plot"`
-	TstEpcPlot    *eplot.Plot2D      `view:"-" desc:"the testing epoch plot"`
-	TstTrlPlot    *eplot.Plot2D      `view:"-" desc:"the test-trial plot"`
-	RunPlot       *eplot.Plot2D      `view:"-" desc:"the run plot"`
-	PowerGridS    *etview.TensorGrid `view:"-" desc:"power grid view for the current segment"`
-	MelFBankGridS *etview.TensorGrid `view:"-" desc:"melfbank grid view for the current segment"`
-	PowerGridL    *etview.TensorGrid `view:"-" desc:"power grid view for the current segment"`
-	MelFBankGridL *etview.TensorGrid `view:"-" desc:"melfbank grid view for the current segment"`
+	Win           *gi.Window           `view:"-" desc:"main GUI window"`
+	NetView       *netview.NetView     `view:"-" desc:"the network viewer"`
+	StructView    *giv.StructView      `view:"-" desc:"the params viewer"`
+	ToolBar       *gi.ToolBar          `view:"-" desc:"the master toolbar"`
+	TrnEpcPlot    *eplot.Plot2D        `view:"-" desc:"the training epoch plot"`
+	TrnTrlPlot    *eplot.Plot2D        `view:"-" desc:"the training trial plot"`
+	TstEpcPlot    *eplot.Plot2D        `view:"-" desc:"the testing epoch plot"`
+	TstTrlPlot    *eplot.Plot2D        `view:"-" desc:"the test-trial plot"`
+	RunPlot       *eplot.Plot2D        `view:"-" desc:"the run plot"`
+	PowerGrids    []*etview.TensorGrid `view:"-" desc:"power grid view for the current segment of each pathway"`
+	MelFBankGrids []*etview.TensorGrid `view:"-" desc:"melfbank grid view for the current segment of each pathway"`
 
 	Comm    *mpi.Comm `view:"-" desc:"mpi communicator"`
 	AllDWts []float32 `view:"-" desc:"buffer of all dwt weight changes -- for mpi sharing"`
@@ -209,6 +233,8 @@ type Sim struct {
 	UseRateSched  bool `desc:"change lrate over epochs using schedule - see LrateSched()"`
 	CalcBtwWthin  bool `desc:"if true calc separate cos diff for between vs within"`
 	CalcPartWhole bool `desc:"if true calc separate cos diff for part words and whole words"`
+	CalcDeps      bool `desc:"if true calc separate cos diff for trained and violated nonadjacent frames"`
+	CalcBwdTP     bool `desc:"if true calc separate cos diff for transitions with high and low backward TP -- for languages where forward TP is flat but backward TP marks the word boundaries"`
 	CalcCosDiff   bool `desc:"if true normal cos diff for all trials"`
 	SaveSimMat    bool `view:"-" desc:"for command-line run only, save simalarity matrix at end of run"`
 	SaveActs      bool `view:"-" desc:"for command-line run only, log activations after each trial"`
@@ -287,12 +313,16 @@ func (ss *Sim) New() {
 	ss.TestRun = false
 	ss.UseRateSched = false
 	ss.CalcCosDiff = true
+	ss.CalcBwdTP = false
+	ss.BwdTPThr = 0.5
 	ss.CalcBtwWthin = true
 	ss.CalcPartWhole = true
 	ss.Pretrain = false
 	ss.RSA.Interval = -1
 	ss.Holdout = false
 	ss.HoldoutPct = 0
+	ss.StimFile = "stimsets.json"
+	ss.PathsFile = ""
 
 	// don't save for gui runs
 	ss.saveProcLog = false
@@ -316,6 +346,10 @@ func (ss *Sim) New() {
 // Config configures all the elements using the standard functions
 func (ss *Sim) Config() {
 	ss.ConfigEnv()
+	if err := ss.Net.SetInputs(ss.Paths); err != nil {
+		log.Println(err)
+		os.Exit(99)
+	}
 	ss.Net.Config()
 	ss.InitStats()
 	ss.ConfigCatLayActs(ss.CatLayActs)
@@ -349,6 +383,7 @@ func (ss *Sim) ConfigEnv() {
 	if ss.MaxPreSeqs == 0 {
 		ss.MaxPreSeqs = 2
 	}
+	ss.OpenPaths()
 
 	ss.TrainEnv.DefaultsTrn()
 	ss.TrainEnv.Nm = "TrainEnv"
@@ -359,6 +394,16 @@ func (ss *Sim) ConfigEnv() {
 	ss.TrainEnv.Sequence.Max = ss.MaxSeqs
 	ss.TrainEnv.Trial.Max = 0
 	ss.TrainEnv.SndTimit = false
+	ss.TrainEnv.Augment.On = ss.Augment
+	ss.TrainEnv.Augment.Seed = ss.AugSeed
+	ss.TrainEnv.Augment.Noise = ss.AugNoise
+	ss.TrainEnv.FrontEnd = ss.FrontEnd
+	ss.TrainEnv.Norm = ss.Norm
+	ss.TrainEnv.SampleRate = ss.SampleRate
+	ss.TrainEnv.FeatCache.On = ss.FeatCache != ""
+	ss.TrainEnv.FeatCache.Path = ss.FeatCache
+	ss.TrainEnv.Prefetch = ss.Prefetch
+	ss.TrainEnv.SetPathways(ss.Paths)
 
 	ss.TestEnv.DefaultsTest()
 	ss.TestEnv.Nm = "TestEnv"
@@ -369,6 +414,13 @@ func (ss *Sim) ConfigEnv() {
 	ss.TestEnv.Sequence.Max = -1
 	ss.TestEnv.Trial.Max = 0
 	ss.TestEnv.SndTimit = false
+	ss.TestEnv.FrontEnd = ss.FrontEnd
+	ss.TestEnv.Norm = ss.Norm
+	ss.TestEnv.SampleRate = ss.SampleRate
+	ss.TestEnv.FeatCache.On = ss.FeatCache != ""
+	ss.TestEnv.FeatCache.Path = ss.FeatCache
+	ss.TestEnv.Prefetch = ss.Prefetch
+	ss.TestEnv.SetPathways(ss.Paths)
 
 	ss.PreTrainEnv.DefaultsTrn()
 	ss.PreTrainEnv.Nm = "PreTrainEnv"
@@ -379,6 +431,16 @@ func (ss *Sim) ConfigEnv() {
 	ss.PreTrainEnv.Sequence.Max = ss.MaxPreSeqs
 	ss.PreTrainEnv.Trial.Max = 0
 	ss.PreTrainEnv.SndTimit = false
+	ss.PreTrainEnv.Augment.On = ss.Augment
+	ss.PreTrainEnv.Augment.Seed = ss.AugSeed
+	ss.PreTrainEnv.Augment.Noise = ss.AugNoise
+	ss.PreTrainEnv.FrontEnd = ss.FrontEnd
+	ss.PreTrainEnv.Norm = ss.Norm
+	ss.PreTrainEnv.SampleRate = ss.SampleRate
+	ss.PreTrainEnv.FeatCache.On = ss.FeatCache != ""
+	ss.PreTrainEnv.FeatCache.Path = ss.FeatCache
+	ss.PreTrainEnv.Prefetch = ss.Prefetch
+	ss.PreTrainEnv.SetPathways(ss.Paths)
 
 	ss.PreTestEnv.DefaultsTest()
 	ss.PreTestEnv.Nm = "PreTestEnv"
@@ -389,12 +451,20 @@ func (ss *Sim) ConfigEnv() {
 	ss.PreTestEnv.Sequence.Max = -1
 	ss.PreTestEnv.Trial.Max = 0
 	ss.PreTestEnv.SndTimit = false
+	ss.PreTestEnv.FrontEnd = ss.FrontEnd
+	ss.PreTestEnv.Norm = ss.Norm
+	ss.PreTestEnv.SampleRate = ss.SampleRate
+	ss.PreTestEnv.FeatCache.On = ss.FeatCache != ""
+	ss.PreTestEnv.FeatCache.Path = ss.FeatCache
+	ss.PreTestEnv.Prefetch = ss.Prefetch
+	ss.PreTestEnv.SetPathways(ss.Paths)
+	ss.SetEnvParams()
 
 	run := ss.TrainEnv.Run.Cur
 	ss.TrainEnv.Init(run)
-	ss.TestEnv.Init(0)
-	ss.PreTrainEnv.Init(0)
-	ss.PreTestEnv.Init(0)
+	ss.TestEnv.Init(run)
+	ss.PreTrainEnv.Init(run)
+	ss.PreTestEnv.Init(run)
 
 	if len(ss.TrnList) == 0 {
 		ss.TrnList = "CVs_I"
@@ -410,6 +480,7 @@ func (ss *Sim) ConfigEnv() {
 	if len(ss.PreTstList) == 0 {
 		ss.PreTstList = "Holdouts"
 	}
+	ss.OpenStimSets()
 	ss.SetTrainingFiles(ss.TrnList)
 	ss.SetTestingFiles(ss.TstList)
 	ss.SetPretrainingFiles(ss.PreTrnList)
@@ -512,10 +583,22 @@ func (ss *Sim) Init() {
 	ss.InitRndSeed()
 	ss.StopNow = false
 	ss.Net.SetParams("", ss.Net.LogSetParams) // all sheets
+	ss.SetEnvParams()
 	ss.NewRun()
 	ss.UpdateView(true) // ToDo: too early - find out why
 }
 
+// SetEnvParams applies the TrainEnv sheets of the params to the train envs and the TestEnv sheets to the
+// test envs, so that a param set can define the auditory processing along with the network
+func (ss *Sim) SetEnvParams() {
+	trn := ss.Net.EnvSheets("TrainEnv")
+	tst := ss.Net.EnvSheets("TestEnv")
+	ss.TrainEnv.SetParams(trn, ss.Net.LogSetParams)
+	ss.PreTrainEnv.SetParams(trn, ss.Net.LogSetParams)
+	ss.TestEnv.SetParams(tst, ss.Net.LogSetParams)
+	ss.PreTestEnv.SetParams(tst, ss.Net.LogSetParams)
+}
+
 // InitRndSeed initializes the random seed based on current training run number
 func (ss *Sim) InitRndSeed() {
 	run := ss.TrainEnv.Run.Cur
@@ -573,14 +656,14 @@ func (ss *Sim) AlphaCyc(train bool) {
 		ss.MPIWtFmDWt()
 	}
 
-	ss.ApplyToA1(ss.Env)
+	ss.ApplyInputs(ss.Env, false)
 	net := ss.Net.Net
 	net.AlphaCycInit()
 	ss.Time.AlphaCycStart()
 	for qtr := 0; qtr < 4; qtr++ {
 		for cyc := 0; cyc < ss.Time.CycPerQtr; cyc++ {
 			if qtr == 0 && cyc == 0 {
-				ss.ApplyToR(ss.Env)
+				ss.ApplyInputs(ss.Env, true)
 			}
 			net.Cycle(&ss.Time)
 			ss.Time.CycleInc()
@@ -620,7 +703,7 @@ func (ss *Sim) AlphaCyc(train bool) {
 	}
 
 	if train {
-		if ss.NoLearn == false || ss.TrainEnv.CurSeg() > 0 { // no learn on first segment of sound - unpredictable
+		if ss.NoLearn == false || ss.TrainEnv.CurSeg() > 0 || ss.TrainEnv.Continuous { // no learn on first segment of sound - unpredictable, unless it continues the last sound
 			net.DWt()
 		}
 	}
@@ -630,25 +713,29 @@ func (ss *Sim) AlphaCyc(train bool) {
 	ss.TrnTrlPlot.GoUpdate()
 }
 
-// ApplyToA1
-func (ss *Sim) ApplyToA1(en env.Env) {
-	net := ss.Net.Net
-	a1s := net.LayerByName("A1").(leabra.LeabraLayer).AsLeabra()
-	if a1s != nil {
-		a1s.InitExt()
-		A1Pat := en.State(a1s.Nm)
-		a1s.ApplyExt(A1Pat)
-	}
-}
-
-// ApplyToR
-func (ss *Sim) ApplyToR(en env.Env) {
+// ApplyInputs applies each pathway to its input layer, e.g. the short pathway to A1, and to A1_1 for the
+// second ear if binaural -- late is true for the pathways applied at the first cycle rather than before
+// the alpha cycle is initialized. The network has an input layer for every pathway, see WordNet.SetInputs.
+func (ss *Sim) ApplyInputs(en env.Env, late bool) {
 	net := ss.Net.Net
-	rs := net.LayerByName("R").(leabra.LeabraLayer).AsLeabra()
-	if rs != nil {
-		rs.InitExt()
-		rsPat := en.State(rs.Nm)
-		rs.ApplyExt(rsPat)
+	for _, pw := range ss.Paths {
+		if pw.Late != late {
+			continue
+		}
+		lays := ss.Net.EarLays(pw.Layer)
+		if pw.FeatLayer != "" && pw.FeatCols() > 0 {
+			lays = append(lays, ss.Net.EarLays(pw.FeatLayer)...)
+		}
+		for _, lnm := range lays {
+			ly, err := net.LayerByNameTry(lnm)
+			if err != nil {
+				continue
+			}
+			lly := ly.(leabra.LeabraLayer).AsLeabra()
+			lly.InitExt()
+			pat := en.State(lly.Nm)
+			lly.ApplyExt(pat)
+		}
 	}
 }
 
@@ -792,6 +879,10 @@ func (ss *Sim) PreTrainTrial() {
 	}
 	ss.TrialFieldUpdates()
 
+	if ss.CalcBtwWthin {
+		ss.PreTrainEnv.CVLookup()
+	}
+
 	//ss.ApplyInputs(ss.Env)
 	ss.AlphaCyc(true)    // train
 	ss.TrnTrlStats(true) // accumulate
@@ -815,10 +906,10 @@ func (ss *Sim) TrialGuiUpdates() {
 		}
 	}
 	if !ss.NoGui {
-		ss.PowerGridS.UpdateSig()
-		ss.MelFBankGridS.UpdateSig()
-		ss.PowerGridL.UpdateSig()
-		ss.MelFBankGridL.UpdateSig()
+		for i := range ss.PowerGrids {
+			ss.PowerGrids[i].UpdateSig()
+			ss.MelFBankGrids[i].UpdateSig()
+		}
 	}
 }
 
@@ -844,6 +935,7 @@ func (ss *Sim) RunEnd() {
 // NewRun intializes a new run of the model, using the TrainEnv.Run counter for the new run value
 func (ss *Sim) NewRun() {
 	ss.InitRndSeed()
+	ss.InitEnvRuns()
 	ss.Time.Reset()
 	ss.OpenTrainedWts(ss.Net.Net)
 	ss.InitStats()
@@ -855,6 +947,22 @@ func (ss *Sim) NewRun() {
 	ss.NeedsNewRun = false
 }
 
+// InitEnvRuns seeds the augmentation of each env with the current run, so that it differs between runs --
+// the test envs follow the run of the env that they test -- and seeds the sound order of each env from
+// the random seed of the run
+func (ss *Sim) InitEnvRuns() {
+	ss.TrainEnv.InitRand(rand.Int63())
+	ss.TestEnv.InitRand(rand.Int63())
+	ss.PreTrainEnv.InitRand(rand.Int63())
+	ss.PreTestEnv.InitRand(rand.Int63())
+	run := ss.TrainEnv.Run.Cur
+	ss.TrainEnv.Augment.Init(run)
+	ss.TestEnv.Init(run)
+	prun := ss.PreTrainEnv.Run.Cur
+	ss.PreTrainEnv.Augment.Init(prun)
+	ss.PreTestEnv.Init(prun)
+}
+
 // OpenTrainedWts
 func (ss *Sim) OpenTrainedWts(net *deep.Network) {
 	if ss.OpenWts {
@@ -886,7 +994,7 @@ func (ss *Sim) InitStats() {
 
 	net.TRCLays = []string{}
 	net.HidLays = []string{}
-	net.SuperLays = []string{"A1", "R"} // A1 and R are input layers - add to super list manually
+	net.SuperLays = append(net.BeltIns("CB"), net.BeltIns("RB")...) // the pathway input layers, e.g. A1 and R - add to super list manually
 
 	for _, ly := range net.Net.Layers {
 		if ly.IsOff() {
@@ -918,6 +1026,18 @@ func (ss *Sim) InitStats() {
 		ss.SumWholeCosDiffTRC = make([]float64, nTRC)
 		ss.EpcPartCosDiffTRC = make([]float64, nTRC)
 		ss.EpcWholeCosDiffTRC = make([]float64, nTRC)
+		ss.SumNonCosDiffTRC = make([]float64, nTRC)
+		ss.EpcNonCosDiffTRC = make([]float64, nTRC)
+
+		ss.SumFrameCosDiffTRC = make([]float64, nTRC)
+		ss.SumViolCosDiffTRC = make([]float64, nTRC)
+		ss.EpcFrameCosDiffTRC = make([]float64, nTRC)
+		ss.EpcViolCosDiffTRC = make([]float64, nTRC)
+
+		ss.SumHiBwdCosDiffTRC = make([]float64, nTRC)
+		ss.SumLoBwdCosDiffTRC = make([]float64, nTRC)
+		ss.EpcHiBwdCosDiffTRC = make([]float64, nTRC)
+		ss.EpcLoBwdCosDiffTRC = make([]float64, nTRC)
 	}
 
 	ss.RSA.Init(net.SuperLays)
@@ -940,7 +1060,8 @@ func (ss *Sim) TrnTrlStats(accum bool) (sse, avgsse, cosdiff float64) {
 	} else {
 		ss.TrlErr = 0
 	}
-	ss.Env.SetIsPredictable() // call every trial
+	ss.Env.SetIsPredictable(&ss.TPs) // call every trial
+	ss.Env.SetTPs(&ss.TPs)
 	ss.TrnTrlStatsTRC(accum)
 	return
 }
@@ -949,22 +1070,31 @@ func (ss *Sim) TrnTrlStats(accum bool) (sse, avgsse, cosdiff float64) {
 func (ss *Sim) TstTrlStats(accum bool) (sse, avgsse, cosdiff float64) {
 	if ss.CalcBtwWthin {
 		if ss.Env == &ss.PreTestEnv {
-			ss.PreTestEnv.SetIsPredictable() // call every trial
+			ss.PreTestEnv.SetIsPredictable(&ss.TPs) // call every trial
 		} else {
-			ss.TestEnv.SetIsPredictable() // call every trial
+			ss.TestEnv.SetIsPredictable(&ss.TPs) // call every trial
 		}
 	}
+	ss.Env.SetTPs(&ss.TPs)
 	if ss.CalcPartWhole { // if we are testing individual "part" words vs individual "whole" words or specific sequence words
 		if ss.Env == &ss.PreTestEnv {
-			ss.PreTestEnv.SetIsPartWhole() // call every trial
+			ss.PreTestEnv.SetIsPartWhole(&ss.TPs) // call every trial
 		} else {
-			ss.TestEnv.SetIsPartWhole() // call every trial
+			ss.TestEnv.SetIsPartWhole(&ss.TPs) // call every trial
 		}
 	}
+	if ss.CalcDeps {
+		ss.Env.SetDependency() // call every trial
+	}
 	if ss.TestType == SequenceTesting {
 		ss.TstTrlStatsTRC(accum)
 	} else if ss.TestType == PartWholeTesting {
 		ss.PartWholeStatsTrc(accum)
+	} else if ss.TestType == DependencyTesting {
+		ss.DependencyStatsTRC(accum)
+	}
+	if ss.CalcBwdTP {
+		ss.BwdTPStatsTRC(accum)
 	}
 	return
 }
@@ -1019,14 +1149,17 @@ func (ss *Sim) TstTrlStatsTRC(accum bool) {
 	}
 }
 
-// PartWholeStatsTrc computes the trial-level statistics for runs where the sequences are part words and whole words
+// PartWholeStatsTrc computes the trial-level statistics for runs where the sequences are part words, whole words and non words
 func (ss *Sim) PartWholeStatsTrc(accum bool) {
 	// == 1 because we only look at the 2nd CV and only the first segment of the 2nd CV
 	if ss.Env.CV.Ordinal == 1 && ss.Env.CV.SubSeg == 0 {
+		nonWord := ss.Env.CV.Word == NonWord && ss.IsTestWordNon(ss.Env.CV.Last, ss.Env.CV.Cur)
 		if ss.Env.CV.Word == PartWord {
 			ss.PartWordCnt++
 		} else if ss.Env.CV.Word == WholeWord {
 			ss.WholeWordCnt++
+		} else if nonWord {
+			ss.NonWordCnt++
 		}
 
 		if accum {
@@ -1035,6 +1168,54 @@ func (ss *Sim) PartWholeStatsTrc(accum bool) {
 					ss.SumPartCosDiffTRC[i] += ss.TrlCosDiffTRC[i]
 				} else if ss.Env.CV.Word == WholeWord {
 					ss.SumWholeCosDiffTRC[i] += ss.TrlCosDiffTRC[i]
+				} else if nonWord {
+					ss.SumNonCosDiffTRC[i] += ss.TrlCosDiffTRC[i]
+				}
+			}
+		}
+	}
+}
+
+// DependencyStatsTRC computes the trial-level statistics for runs where the sequences are trained and violated nonadjacent frames
+func (ss *Sim) DependencyStatsTRC(accum bool) {
+	// only the first segment of the CV in the dependent position
+	if ss.Env.CV.DepDist > 0 && ss.Env.CV.SubSeg == 0 {
+		if ss.Env.CV.Dep == TrainedFrame {
+			ss.FrameCnt++
+		} else if ss.Env.CV.Dep == ViolatedFrame {
+			ss.ViolCnt++
+		}
+
+		if accum {
+			for i := range ss.Net.TRCLays {
+				if ss.Env.CV.Dep == TrainedFrame {
+					ss.SumFrameCosDiffTRC[i] += ss.TrlCosDiffTRC[i]
+				} else if ss.Env.CV.Dep == ViolatedFrame {
+					ss.SumViolCosDiffTRC[i] += ss.TrlCosDiffTRC[i]
+				}
+			}
+		}
+	}
+}
+
+// BwdTPStatsTRC computes the trial-level statistics for transitions with high vs low backward TP.
+// The network only predicts forward, so a difference here means the prediction error tracks the backward structure.
+func (ss *Sim) BwdTPStatsTRC(accum bool) {
+	// only the first segment of a CV that follows another CV
+	if ss.Env.CV.Ordinal > 0 && ss.Env.CV.SubSeg == 0 && ss.Env.CV.Cur != "ss" {
+		hi := ss.Env.CV.BwdTP >= ss.BwdTPThr
+		if hi {
+			ss.HiBwdCnt++
+		} else {
+			ss.LoBwdCnt++
+		}
+
+		if accum {
+			for i := range ss.Net.TRCLays {
+				if hi {
+					ss.SumHiBwdCosDiffTRC[i] += ss.TrlCosDiffTRC[i]
+				} else {
+					ss.SumLoBwdCosDiffTRC[i] += ss.TrlCosDiffTRC[i]
 				}
 			}
 		}
@@ -1060,13 +1241,46 @@ func (ss *Sim) EpochStatsTRC(nt float64) {
 		ss.SumPartCosDiffTRC[i] = 0
 		ss.EpcWholeCosDiffTRC[i] = ss.SumWholeCosDiffTRC[i] / float64(ss.WholeWordCnt)
 		ss.SumWholeCosDiffTRC[i] = 0
+		ss.EpcNonCosDiffTRC[i] = 0
+		if ss.NonWordCnt > 0 {
+			ss.EpcNonCosDiffTRC[i] = ss.SumNonCosDiffTRC[i] / float64(ss.NonWordCnt)
+		}
+		ss.SumNonCosDiffTRC[i] = 0
+
+		ss.EpcFrameCosDiffTRC[i] = 0
+		if ss.FrameCnt > 0 {
+			ss.EpcFrameCosDiffTRC[i] = ss.SumFrameCosDiffTRC[i] / float64(ss.FrameCnt)
+		}
+		ss.SumFrameCosDiffTRC[i] = 0
+		ss.EpcViolCosDiffTRC[i] = 0
+		if ss.ViolCnt > 0 {
+			ss.EpcViolCosDiffTRC[i] = ss.SumViolCosDiffTRC[i] / float64(ss.ViolCnt)
+		}
+		ss.SumViolCosDiffTRC[i] = 0
 
+		ss.EpcHiBwdCosDiffTRC[i] = 0
+		if ss.HiBwdCnt > 0 {
+			ss.EpcHiBwdCosDiffTRC[i] = ss.SumHiBwdCosDiffTRC[i] / float64(ss.HiBwdCnt)
+		}
+		ss.SumHiBwdCosDiffTRC[i] = 0
+		ss.EpcLoBwdCosDiffTRC[i] = 0
+		if ss.LoBwdCnt > 0 {
+			ss.EpcLoBwdCosDiffTRC[i] = ss.SumLoBwdCosDiffTRC[i] / float64(ss.LoBwdCnt)
+		}
+		ss.SumLoBwdCosDiffTRC[i] = 0
 	}
 	ss.BtwWordCnt = 0 // reset for next epoch
 	ss.InWordCnt = 0
 
 	ss.PartWordCnt = 0
 	ss.WholeWordCnt = 0
+	ss.NonWordCnt = 0
+
+	ss.FrameCnt = 0
+	ss.ViolCnt = 0
+
+	ss.HiBwdCnt = 0
+	ss.LoBwdCnt = 0
 }
 
 // HogDead computes the proportion of units in given layer name with ActAvg over hog thr
@@ -1251,6 +1465,7 @@ func (ss *Sim) TrainInit() {
 		ss.TestEnv.SndList = "testHoldouts_" + ss.HoldoutID + "_" + strconv.Itoa(r) + ".txt"
 		ss.TrainEnv.SplitSndFiles(ss.TestEnv.SndList)
 	}
+	ss.TrainEnv.CalcTPs(&ss.TPs)
 	ss.TrainEnv.Epoch.Max = ss.MaxEpcs
 	ss.TrainEnv.Sequence.Max = ss.MaxSeqs
 	ss.CalcBtwWthin = true
@@ -1289,11 +1504,12 @@ func (ss *Sim) PreTrainInit() {
 		ss.PreTestEnv.SndList = "testHoldouts_" + ss.HoldoutID + "_" + strconv.Itoa(r) + ".txt"
 		ss.TrainEnv.SplitSndFiles(ss.PreTestEnv.SndList)
 	}
+	ss.TrainEnv.CalcTPs(&ss.TPs)
 	ss.PreTrainEnv.Run.Max = 1
 	ss.PreTrainEnv.Epoch.Max = ss.MaxPreEpcs
 	ss.PreTrainEnv.Sequence.Max = ss.MaxPreSeqs
 
-	ss.CalcBtwWthin = false
+	ss.CalcBtwWthin = ss.PreTrainEnv.Words // natural speech word boundaries
 	ss.CalcPartWhole = false
 }
 
@@ -1359,6 +1575,12 @@ func (ss *Sim) TestInit() {
 	ss.Env.Sequence.Max = len(ss.Env.SndFiles)
 	ss.Env.Silence = true
 	ss.CalcBtwWthin = true
+	if ss.TPs.NSeqs == 0 { // testing without training first
+		if len(ss.TrainEnv.SndFiles) == 0 {
+			ss.TrainEnv.LoadWavNames()
+		}
+		ss.TrainEnv.CalcTPs(&ss.TPs)
+	}
 }
 
 // TestTrial runs one trial of testing -- always sequentially presented inputs
@@ -1396,7 +1618,7 @@ func (ss *Sim) TestTrial(epcFile *LogFile, epcFileTidy *LogFile) {
 	}
 	ss.TrialFieldUpdates()
 
-	if ss.CalcBtwWthin {
+	if ss.CalcBtwWthin || ss.CalcDeps || ss.CalcBwdTP {
 		ss.Env.CVLookup()
 	}
 
@@ -1689,6 +1911,11 @@ func (ss *Sim) ConfigTrnTrlLog(dt *etable.Table) {
 		{"SSE", etensor.FLOAT64, nil, nil},
 		{"AvgSSE", etensor.FLOAT64, nil, nil},
 		{"Segment", etensor.FLOAT64, nil, nil},
+		{"FwdTP", etensor.FLOAT64, nil, nil},
+		{"BwdTP", etensor.FLOAT64, nil, nil},
+		{"BiFreq", etensor.FLOAT64, nil, nil},
+		{"Prosody", etensor.STRING, nil, nil},
+		{"WordPos", etensor.STRING, nil, nil},
 	}
 
 	for _, lnm := range ss.Net.TRCLays {
@@ -1714,21 +1941,26 @@ func (ss *Sim) LogTrnTrl(dt *etable.Table) {
 	dt.SetCellFloat("Epoch", row, float64(ss.TrainEnv.Epoch.Cur))
 	dt.SetCellFloat("Trial", row, float64(ss.TrainEnv.Trial.Cur))
 	dt.SetCellFloat("Segment", row, float64(ss.TrainEnv.CurSeg())*.01)
+	dt.SetCellFloat("FwdTP", row, ss.TrainEnv.CV.FwdTP)
+	dt.SetCellFloat("BwdTP", row, ss.TrainEnv.CV.BwdTP)
+	dt.SetCellFloat("BiFreq", row, ss.TrainEnv.CV.BiFreq)
+	dt.SetCellString("Prosody", row, ss.Env.CueCond().String())
+	dt.SetCellString("WordPos", row, ss.Env.CV.WordPos.String())
 	dt.SetCellFloat("Err", row, ss.TrlErr)
 	dt.SetCellFloat("SSE", row, ss.TrlSSE)
 	dt.SetCellFloat("AvgSSE", row, ss.TrlAvgSSE)
 
-	// are we within a word or at start of word
+	// are we within a word or at start of word -- ss.Env is the PreTrainEnv when pretraining
 	if ss.CalcBtwWthin {
-		if ss.TrainEnv.CV.SubSeg == 0 { // only saving stat for first segment of CV
-			last := ss.TrainEnv.CV.Last
-			cur := ss.TrainEnv.CV.Cur
-			if ss.TrainEnv.CV.Predictable == Partially && ss.IsTestWordPart(last, cur) {
+		if ss.Env.CV.SubSeg == 0 { // only saving stat for first segment of CV
+			last := ss.Env.CV.Last
+			cur := ss.Env.CV.Cur
+			if ss.Env.CV.Predictable == Partially && ss.IsTestWordPart(last, cur) {
 				for i, lnm := range ss.Net.TRCLays {
 					dt.SetCellFloat(lnm+" CosDiff_Btw", row, float64(ss.TrlCosDiffTRC[i]))
 					dt.SetCellFloat(lnm+" CosDiff_InWord", row, float64(0))
 				}
-			} else if ss.TrainEnv.CV.Predictable == Fully && ss.IsTestWordWhole(last, cur) {
+			} else if ss.Env.CV.Predictable == Fully && ss.IsTestWordWhole(last, cur) {
 				for i, lnm := range ss.Net.TRCLays {
 					dt.SetCellFloat(lnm+" CosDiff_InWord", row, float64(ss.TrlCosDiffTRC[i]))
 					dt.SetCellFloat(lnm+" CosDiff_Btw", row, float64(0))
@@ -1805,6 +2037,12 @@ func (ss *Sim) ConfigTstTrlLog(dt *etable.Table) {
 		{"Epoch", etensor.INT64, nil, nil},
 		{"Trial", etensor.INT64, nil, nil},
 		{"Segment", etensor.FLOAT64, nil, nil},
+		{"FwdTP", etensor.FLOAT64, nil, nil},
+		{"BwdTP", etensor.FLOAT64, nil, nil},
+		{"BiFreq", etensor.FLOAT64, nil, nil},
+		{"DepDist", etensor.INT64, nil, nil},
+		{"Prosody", etensor.STRING, nil, nil},
+		{"WordPos", etensor.STRING, nil, nil},
 		{"CosDiff", etensor.FLOAT64, nil, nil},
 	}
 
@@ -1816,6 +2054,11 @@ func (ss *Sim) ConfigTstTrlLog(dt *etable.Table) {
 		if ss.CalcPartWhole {
 			sch = append(sch, etable.Column{lnm + " CosDiff_WholeWord", etensor.FLOAT64, nil, nil})
 			sch = append(sch, etable.Column{lnm + " CosDiff_PartWord", etensor.FLOAT64, nil, nil})
+			sch = append(sch, etable.Column{lnm + " CosDiff_NonWord", etensor.FLOAT64, nil, nil})
+		}
+		if ss.CalcDeps {
+			sch = append(sch, etable.Column{lnm + " CosDiff_Frame", etensor.FLOAT64, nil, nil})
+			sch = append(sch, etable.Column{lnm + " CosDiff_Violated", etensor.FLOAT64, nil, nil})
 		}
 		if ss.CalcCosDiff {
 			sch = append(sch, etable.Column{lnm + " CosDiff", etensor.FLOAT64, nil, nil})
@@ -1836,6 +2079,12 @@ func (ss *Sim) LogTstTrl(dt *etable.Table) {
 	dt.SetCellFloat("Epoch", row, float64(ss.TestEnv.Epoch.Cur))
 	dt.SetCellFloat("Trial", row, float64(ss.TestEnv.Trial.Cur))
 	dt.SetCellFloat("Segment", row, float64(ss.TestEnv.CurSeg())*.01)
+	dt.SetCellFloat("FwdTP", row, ss.TestEnv.CV.FwdTP)
+	dt.SetCellFloat("BwdTP", row, ss.TestEnv.CV.BwdTP)
+	dt.SetCellFloat("BiFreq", row, ss.TestEnv.CV.BiFreq)
+	dt.SetCellFloat("DepDist", row, float64(ss.TestEnv.CV.DepDist))
+	dt.SetCellString("Prosody", row, ss.TestEnv.CueCond().String())
+	dt.SetCellString("WordPos", row, ss.Env.CV.WordPos.String())
 
 	// are we within a word or at start of word
 	if ss.TestType == SequenceTesting && ss.CalcBtwWthin { // only saving stat for first segment of CV
@@ -1873,16 +2122,46 @@ func (ss *Sim) LogTstTrl(dt *etable.Table) {
 				for i, lnm := range ss.Net.TRCLays {
 					dt.SetCellFloat(lnm+" CosDiff_PartWord", row, float64(ss.TrlCosDiffTRC[i]))
 					dt.SetCellFloat(lnm+" CosDiff_WholeWord", row, float64(0))
+					dt.SetCellFloat(lnm+" CosDiff_NonWord", row, float64(0))
 				}
 			} else if ss.TestEnv.CV.Word == WholeWord {
 				for i, lnm := range ss.Net.TRCLays {
 					dt.SetCellFloat(lnm+" CosDiff_WholeWord", row, float64(ss.TrlCosDiffTRC[i]))
 					dt.SetCellFloat(lnm+" CosDiff_PartWord", row, float64(0))
+					dt.SetCellFloat(lnm+" CosDiff_NonWord", row, float64(0))
+				}
+			} else if ss.TestEnv.CV.Word == NonWord && ss.IsTestWordNon(ss.TestEnv.CV.Last, ss.TestEnv.CV.Cur) {
+				for i, lnm := range ss.Net.TRCLays {
+					dt.SetCellFloat(lnm+" CosDiff_NonWord", row, float64(ss.TrlCosDiffTRC[i]))
+					dt.SetCellFloat(lnm+" CosDiff_WholeWord", row, float64(0))
+					dt.SetCellFloat(lnm+" CosDiff_PartWord", row, float64(0))
 				}
 			} else {
 				for _, lnm := range ss.Net.TRCLays {
 					dt.SetCellFloat(lnm+" CosDiff_WholeWord", row, float64(0))
 					dt.SetCellFloat(lnm+" CosDiff_PartWord", row, float64(0))
+					dt.SetCellFloat(lnm+" CosDiff_NonWord", row, float64(0))
+				}
+			}
+		}
+	}
+	if ss.TestType == DependencyTesting && ss.CalcDeps {
+		// is the CV in the dependent position of a trained or a violated frame
+		if ss.TestEnv.CV.SubSeg == 0 { // only get stat for first segment of the CV
+			if ss.TestEnv.CV.Dep == TrainedFrame {
+				for i, lnm := range ss.Net.TRCLays {
+					dt.SetCellFloat(lnm+" CosDiff_Frame", row, float64(ss.TrlCosDiffTRC[i]))
+					dt.SetCellFloat(lnm+" CosDiff_Violated", row, float64(0))
+				}
+			} else if ss.TestEnv.CV.Dep == ViolatedFrame {
+				for i, lnm := range ss.Net.TRCLays {
+					dt.SetCellFloat(lnm+" CosDiff_Violated", row, float64(ss.TrlCosDiffTRC[i]))
+					dt.SetCellFloat(lnm+" CosDiff_Frame", row, float64(0))
+				}
+			} else {
+				for _, lnm := range ss.Net.TRCLays {
+					dt.SetCellFloat(lnm+" CosDiff_Frame", row, float64(0))
+					dt.SetCellFloat(lnm+" CosDiff_Violated", row, float64(0))
 				}
 			}
 		}
@@ -1925,6 +2204,11 @@ func (ss *Sim) ConfigTstTrlPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 		if ss.CalcPartWhole {
 			plt.SetColParams(lnm+" CosDiff_PartWord", on, true, 0, true, 1)
 			plt.SetColParams(lnm+" CosDiff_WholeWord", on, true, 0, true, 1)
+			plt.SetColParams(lnm+" CosDiff_NonWord", on, true, 0, true, 1)
+		}
+		if ss.CalcDeps {
+			plt.SetColParams(lnm+" CosDiff_Frame", on, true, 0, true, 1)
+			plt.SetColParams(lnm+" CosDiff_Violated", on, true, 0, true, 1)
 		}
 		if ss.CalcCosDiff {
 			plt.SetColParams(lnm+" CosDiff", false, true, 0, true, 1)
@@ -1956,6 +2240,15 @@ func (ss *Sim) ConfigTstEpcLog(dt *etable.Table) {
 		if ss.CalcPartWhole {
 			sch = append(sch, etable.Column{lnm + " CosDiff_PartWord", etensor.FLOAT64, nil, nil})
 			sch = append(sch, etable.Column{lnm + " CosDiff_WholeWord", etensor.FLOAT64, nil, nil})
+			sch = append(sch, etable.Column{lnm + " CosDiff_NonWord", etensor.FLOAT64, nil, nil})
+		}
+		if ss.CalcDeps {
+			sch = append(sch, etable.Column{lnm + " CosDiff_Frame", etensor.FLOAT64, nil, nil})
+			sch = append(sch, etable.Column{lnm + " CosDiff_Violated", etensor.FLOAT64, nil, nil})
+		}
+		if ss.CalcBwdTP {
+			sch = append(sch, etable.Column{lnm + " CosDiff_HiBwdTP", etensor.FLOAT64, nil, nil})
+			sch = append(sch, etable.Column{lnm + " CosDiff_LoBwdTP", etensor.FLOAT64, nil, nil})
 		}
 		if ss.CalcCosDiff {
 			sch = append(sch, etable.Column{lnm + " CosDiff", etensor.FLOAT64, nil, nil})
@@ -1994,6 +2287,15 @@ func (ss *Sim) LogTstEpc(dt *etable.Table, logFile *LogFile) {
 		if ss.TestType == PartWholeTesting && ss.CalcPartWhole {
 			dt.SetCellFloat(lnm+" CosDiff_PartWord", row, float64(ss.EpcPartCosDiffTRC[i]))
 			dt.SetCellFloat(lnm+" CosDiff_WholeWord", row, float64(ss.EpcWholeCosDiffTRC[i]))
+			dt.SetCellFloat(lnm+" CosDiff_NonWord", row, float64(ss.EpcNonCosDiffTRC[i]))
+		}
+		if ss.TestType == DependencyTesting && ss.CalcDeps {
+			dt.SetCellFloat(lnm+" CosDiff_Frame", row, float64(ss.EpcFrameCosDiffTRC[i]))
+			dt.SetCellFloat(lnm+" CosDiff_Violated", row, float64(ss.EpcViolCosDiffTRC[i]))
+		}
+		if ss.CalcBwdTP {
+			dt.SetCellFloat(lnm+" CosDiff_HiBwdTP", row, float64(ss.EpcHiBwdCosDiffTRC[i]))
+			dt.SetCellFloat(lnm+" CosDiff_LoBwdTP", row, float64(ss.EpcLoBwdCosDiffTRC[i]))
 		}
 		if ss.CalcCosDiff {
 			dt.SetCellFloat(lnm+" CosDiff", row, float64(ss.EpcCosDiffTRC[i]))
@@ -2042,6 +2344,14 @@ func (ss *Sim) LogTstEpcTidy(dt *etable.Table, logFile *LogFile) {
 	dt.SetNumRows(row + 1)
 
 	conditions := []string{"in---word", "next-word"} // same length for aligning tabs
+	if ss.TestType == PartWholeTesting {
+		conditions = []string{"whole-word", "part--word", "non---word"}
+	} else if ss.TestType == DependencyTesting {
+		conditions = []string{"trained-frame", "violate-frame"}
+	}
+	if ss.CalcBwdTP {
+		conditions = append(conditions, "hi-bwd-tp", "lo-bwd-tp")
+	}
 	for l, lnm := range ss.Net.TRCLays {
 		for _, cond := range conditions {
 			if ss.Env == &ss.PreTestEnv {
@@ -2055,10 +2365,25 @@ func (ss *Sim) LogTstEpcTidy(dt *etable.Table, logFile *LogFile) {
 			}
 			dt.SetCellString("Layer", row, lnm)
 			dt.SetCellString("Condition", row, cond)
-			if cond == "in---word" {
+			switch cond {
+			case "in---word":
 				dt.SetCellFloat("Cosine", row, ss.EpcInWordCosDiffTRC[l])
-			} else {
+			case "next-word":
 				dt.SetCellFloat("Cosine", row, ss.EpcBtwCosDiffTRC[l])
+			case "whole-word":
+				dt.SetCellFloat("Cosine", row, ss.EpcWholeCosDiffTRC[l])
+			case "part--word":
+				dt.SetCellFloat("Cosine", row, ss.EpcPartCosDiffTRC[l])
+			case "non---word":
+				dt.SetCellFloat("Cosine", row, ss.EpcNonCosDiffTRC[l])
+			case "trained-frame":
+				dt.SetCellFloat("Cosine", row, ss.EpcFrameCosDiffTRC[l])
+			case "violate-frame":
+				dt.SetCellFloat("Cosine", row, ss.EpcViolCosDiffTRC[l])
+			case "hi-bwd-tp":
+				dt.SetCellFloat("Cosine", row, ss.EpcHiBwdCosDiffTRC[l])
+			case "lo-bwd-tp":
+				dt.SetCellFloat("Cosine", row, ss.EpcLoBwdCosDiffTRC[l])
 			}
 
 			if ss.saveTstEpcTidy == true && (ss.saveProcLog || mpi.WorldRank() == 0) {
@@ -2094,6 +2419,15 @@ func (ss *Sim) ConfigTstEpcPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 		if ss.CalcPartWhole {
 			plt.SetColParams(lnm+" CosDiff_PartWord", false, true, 0, true, 1)
 			plt.SetColParams(lnm+" CosDiff_WholeWord", true, true, 0, true, 1)
+			plt.SetColParams(lnm+" CosDiff_NonWord", false, true, 0, true, 1)
+		}
+		if ss.CalcDeps {
+			plt.SetColParams(lnm+" CosDiff_Frame", true, true, 0, true, 1)
+			plt.SetColParams(lnm+" CosDiff_Violated", false, true, 0, true, 1)
+		}
+		if ss.CalcBwdTP {
+			plt.SetColParams(lnm+" CosDiff_HiBwdTP", true, true, 0, true, 1)
+			plt.SetColParams(lnm+" CosDiff_LoBwdTP", false, true, 0, true, 1)
 		}
 		if ss.CalcCosDiff {
 			plt.SetColParams(lnm+" CosDiff", true, true, 0, true, 1)
@@ -2199,7 +2533,7 @@ func (ss *Sim) ResetTestSet() {
 		ss.Win.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 			if sig == int64(gi.DialogAccepted) {
 				ss.TstList = gi.StringPromptDialogValue(dlg)
-				ss.SetTestingFiles(nm)
+				ss.SetTestingFiles(ss.TstList)
 			}
 		})
 }
@@ -2346,31 +2680,22 @@ func (ss *Sim) ConfigGui() *gi.Window {
 	ss.RunPlot = plt
 	ss.ConfigRunPlot(plt, ss.RunLog)
 
-	// sound short
-	tgS := tv.AddNewTab(etview.KiT_TensorGrid, "MelFBank").(*etview.TensorGrid)
-	tgS.SetStretchMax()
-	ss.MelFBankGridS = tgS
-	tgS.SetTensor(&ss.TrainEnv.SndShort.MelFBankSegment)
-
-	// sound long
-	tgL := tv.AddNewTab(etview.KiT_TensorGrid, "MelFBank").(*etview.TensorGrid)
-	tgL.SetStretchMax()
-	ss.MelFBankGridL = tgL
-	tgL.SetTensor(&ss.TrainEnv.SndLong.MelFBankSegment)
-
-	// sound short
-	tgS = tv.AddNewTab(etview.KiT_TensorGrid, "Power").(*etview.TensorGrid)
-	tgS.SetStretchMax()
-	ss.PowerGridS = tgS
-	ss.TrainEnv.SndShort.LogPowerSegment.SetMetaData("grid-min", "10")
-	tgS.SetTensor(&ss.TrainEnv.SndShort.LogPowerSegment)
-
-	// sound long
-	tgL = tv.AddNewTab(etview.KiT_TensorGrid, "Power").(*etview.TensorGrid)
-	tgL.SetStretchMax()
-	ss.PowerGridL = tgL
-	ss.TrainEnv.SndLong.LogPowerSegment.SetMetaData("grid-min", "10")
-	tgL.SetTensor(&ss.TrainEnv.SndLong.LogPowerSegment)
+	// a mel filterbank and a power tab for each pathway
+	ss.MelFBankGrids = nil
+	ss.PowerGrids = nil
+	for _, se := range ss.TrainEnv.Snds {
+		tg := tv.AddNewTab(etview.KiT_TensorGrid, "MelFBank").(*etview.TensorGrid)
+		tg.SetStretchMax()
+		ss.MelFBankGrids = append(ss.MelFBankGrids, tg)
+		tg.SetTensor(&se.MelFBankSegment)
+	}
+	for _, se := range ss.TrainEnv.Snds {
+		tg := tv.AddNewTab(etview.KiT_TensorGrid, "Power").(*etview.TensorGrid)
+		tg.SetStretchMax()
+		ss.PowerGrids = append(ss.PowerGrids, tg)
+		se.LogPowerSegment.SetMetaData("grid-min", "10")
+		tg.SetTensor(&se.LogPowerSegment)
+	}
 
 	tbar.AddAction(gi.ActOpts{Label: "Init", Icon: "update", Tooltip: "Initialize everything including network weights, and start over.  Also applies current params.", UpdateFunc: func(act *gi.Action) {
 		act.SetActiveStateUpdt(!ss.IsRunning)
@@ -2549,15 +2874,21 @@ func (ss *Sim) CmdArgs() {
 	ss.NoGui = true
 	var nogui bool
 	var note string
+	var augNoise string
+	var frontEnd string
+	var norm string
 	saveNetData := false
 
 	flag.BoolVar(&nogui, "nogui", true, "if not passing any other args and want to run nogui, use nogui")
 	flag.StringVar(&ss.Net.ParamSet, "params", "", "ParamSet name to use -- must be valid name as listed in compiled-in params or loaded params")
 	flag.BoolVar(&ss.Net.LogSetParams, "setparams", false, "if true, print a record of each parameter that is set")
+	flag.BoolVar(&ss.Net.Binaural, "binaural", false, "if true, the network has separate input layers for the two ears (channels) of stereo sounds -- use a stimulus set with Channel -1")
 	flag.StringVar(&ss.TrnList, "trnlist", "", "identifies the list of sound stimuli for train environment")
 	flag.StringVar(&ss.TstList, "tstlist", "", "identifies the list of sound stimuli for test environment")
 	flag.StringVar(&ss.PreTrnList, "prelist", "", "identifies the list of sound stimuli for pretrain environment")
 	flag.StringVar(&ss.PreTstList, "pretstlist", "", "identifies the list of sound stimuli for test environment")
+	flag.StringVar(&ss.StimFile, "stimfile", "stimsets.json", "JSON file describing the stimulus sets named by -trnlist, -tstlist, -prelist and -pretstlist")
+	flag.StringVar(&ss.PathsFile, "pathways", "", "JSON file defining the auditory pathways and the input layers they are applied to -- empty for the default short and long pathways")
 	flag.StringVar(&ss.Tag, "tag", "", "extra tag to add to file names saved from this run")
 	flag.StringVar(&ss.HoldoutID, "holdoutid", "", "unique id for testing holdout file name")
 	flag.StringVar(&note, "note", "", "user note -- not used")
@@ -2596,9 +2927,28 @@ func (ss *Sim) CmdArgs() {
 	flag.BoolVar(&ss.TestRun, "test", false, "true for test instead of train")
 	flag.BoolVar(&ss.CalcBtwWthin, "calcbtw", true, "calculates cos diff for between and within trials separately")
 	flag.BoolVar(&ss.CalcCosDiff, "calccosdif", true, "calculates cos diff across all trials")
+	flag.BoolVar(&ss.CalcBwdTP, "calcbwdtp", false, "calculates separate test cos diff for transitions with high and low backward TP")
+	flag.Float64Var(&ss.BwdTPThr, "bwdtpthr", 0.5, "backward TP at or above which a transition counts as high backward TP")
+	flag.BoolVar(&ss.Augment, "augment", false, "if true, randomly shift the pitch, stretch the tempo, change the level and add noise to each training sound")
+	flag.Int64Var(&ss.AugSeed, "augseed", 0, "seed for the augmentation, the run number is added to it")
+	flag.StringVar(&augNoise, "augnoise", "NoNoise", "type of noise the augmentation adds: NoNoise, WhiteNoise or PinkNoise")
+	flag.BoolVar(&ss.Prefetch, "prefetch", false, "if true, load and process the next sound in the background while the network runs the current one")
+	flag.StringVar(&ss.FeatCache, "featcache", "", "directory of the on-disk cache of the processed sound segments -- empty for no cache")
+	flag.StringVar(&frontEnd, "frontend", "MelGabor", "auditory preprocessing: MelGabor, Mfcc or Gammatone")
+	flag.IntVar(&ss.SampleRate, "samplerate", 0, "sample rate every sound is resampled to when loaded, e.g. 16000 to make corpora of different rates alike -- 0 keeps the rate of each sound")
+	flag.StringVar(&norm, "norm", "FixedRenorm", "normalization of the filterbank output: FixedRenorm, UttCMVN, RunningNorm or BandAGC")
 	flag.BoolVar(&saveNetData, "netdata", false, "if true, save network activation etc data from testing trials, for later viewing in netview")
 	flag.IntVar(&ss.HoldoutPct, "holdoutpct", 34, "percentage of items to holdout from train set for testing")
 	flag.Parse()
+	if err := ss.AugNoise.FromString(augNoise); err != nil {
+		log.Println(err)
+	}
+	if err := ss.FrontEnd.FromString(frontEnd); err != nil {
+		log.Println(err)
+	}
+	if err := ss.Norm.FromString(norm); err != nil {
+		log.Println(err)
+	}
 
 	if ss.UseMPI {
 		fmt.Println("use mpi")
@@ -2806,101 +3156,67 @@ func (ss *Sim) MPIWtFmDWt() {
 	net.WtFmDWt()
 }
 
-// SetTrainingFiles
+// OpenStimSets loads the stimulus sets from StimFile - the -trnlist, -tstlist, etc args name one of these sets
+func (ss *Sim) OpenStimSets() {
+	err := ss.StimSets.OpenJSON(gi.FileName(ss.StimFile))
+	if err != nil {
+		log.Println("Make sure the stimulus sets file is in your sim working directory or use -stimfile to give its path")
+	}
+}
+
+// OpenPaths loads the auditory pathways from PathsFile, or sets the default pathways if there is no file
+func (ss *Sim) OpenPaths() {
+	if ss.PathsFile == "" {
+		ss.Paths = DefaultPathways()
+		return
+	}
+	err := ss.Paths.OpenJSON(gi.FileName(ss.PathsFile))
+	if err != nil || len(ss.Paths) == 0 {
+		log.Println("Make sure the pathways file is in your sim working directory or use -pathways to give its path -- using the default pathways")
+		ss.Paths = DefaultPathways()
+	}
+}
+
+// SetTrainingFiles sets the training environment from the stimulus set named by key
 func (ss *Sim) SetTrainingFiles(key string) {
-	// Train Train Train Train
-	ss.TrainEnv.SndPath = "ccn_images/word_seg_snd_files/"
-
-	train := true
-	switch ss.TrnList {
-	case "CVs_I":
-		ss.TrainEnv.SeqsPath = "CV_I_NoCoAr_Seqs/"
-		ss.TrainEnv.WavsPath = "CV_I_NoCoAr_Wavs/"
-		ss.TrainEnv.TimesPath = "CV_I_NoCoAr_Times/"
-		ss.TrainEnv.CVsPerWord = 3 // The saffran, et al experiment used 3 syllable words
-		ss.TrainEnv.CVsPerPos = 4  // The saffran, et al experiment had 4 cv possibilities per syllable position
-
-	case "CVs_III", "CVs_IV", "CVs_V", "CVs_VI":
-		ss.TrainEnv.SeqsPath = "CV_III_IV_V_VI_NoCoAr_Seqs/"
-		ss.TrainEnv.WavsPath = "CV_III_IV_V_VI_NoCoAr_Wavs/"
-		ss.TrainEnv.TimesPath = "CV_III_IV_V_VI_NoCoAr_Times/"
-		ss.TrainEnv.CVsPerWord = 3 // The saffran, et al experiment used 3 syllable words, Graf Estes 2
-		ss.TrainEnv.CVsPerPos = 4  // The saffran, et al experiment had 4 cv possibilities per syllable position
-
-	case "MT_IABC":
-		// for graf-estes simulation
-		ss.TrainEnv.SeqsPath = "MT_IABC_Seqs/"
-		ss.TrainEnv.WavsPath = "MT_IABC_Wavs/"
-		ss.TrainEnv.TimesPath = "MT_IABC_Times/"
-		ss.TrainEnv.CVsPerWord = 2 // The graf-estes experiment used 2 syllable words
-		ss.TrainEnv.CVsPerPos = 4  // The graf-estes experiment had 4 cv possibilities per syllable position
-
-	case "TIMIT_ALL_SX_F":
-		ss.TrainEnv.SndPath = "ccn_images/sound/timit/" // this one is different!
-		ss.TrainEnv.WavsPath = "TIMIT/TRAIN/"
-		ss.TrainEnv.SeqsPath = "TIMIT/TRAIN/"
-		ss.TrainEnv.TimesPath = "TIMIT/TRAIN/"
-		ss.TrainEnv.SndTimit = true
+	st, err := ss.StimSets.ByNameTry(key)
+	if err != nil {
+		fmt.Println("No matching sound set for training - ok if doing pretrain or testing")
+		return
+	}
+	ss.TrainEnv.SetStimSet(st)
+	if st.Timit {
 		ss.CalcPartWhole = false
 		ss.CalcBtwWthin = true
 		ss.SaveActs = false
-
-	default:
-		fmt.Println("No matching sound set for training - ok if doing pretrain or testing")
-		train = false
+	}
+	if len(st.TestWordsPart) > 0 || len(st.TestWordsWhole) > 0 || len(st.TestWordsNon) > 0 {
+		ss.TestWordsPart = st.TestWordsPart
+		ss.TestWordsWhole = st.TestWordsWhole
+		ss.TestWordsNon = st.TestWordsNon
 	}
 
-	if train {
-		switch ss.TrnList {
-		case "CVs_I":
-			ss.TrainEnv.SndList = "CV_I_NoCoAr_Train.txt"
-			ss.TrainEnv.CVs = CVs_I
-		case "CVs_III":
-			ss.TrainEnv.SndList = "CV_III_NoCoAr_Train.txt"
-			ss.TrainEnv.CVs = CVs_III
-		case "CVs_IV":
-			ss.TrainEnv.SndList = "CV_IV_NoCoAr_Train.txt"
-			ss.TrainEnv.CVs = CVs_IV
-		case "CVs_V":
-			ss.TrainEnv.SndList = "CV_V_NoCoAr_Train.txt"
-			ss.TrainEnv.CVs = CVs_V
-		case "CVs_VI":
-			ss.TrainEnv.SndList = "CV_VI_NoCoAr_Train.txt"
-			ss.TrainEnv.CVs = CVs_VI
-		case "MT_IABC":
-			ss.TrainEnv.SndList = "MT_IABC_Train.txt"
-			ss.TrainEnv.CVs = CVs_MT
-			ss.TrainEnv.Silence = true
-			ss.TestWordsPart = []string{"ku ga", "pi mo"}
-			ss.TestWordsWhole = []string{"do bu", "ti may"}
-		case "TIMIT_ALL_SX_F":
-			ss.TrainEnv.SndList = "trainAllFemaleSX.txt"
-			ss.TrainEnv.CVs = []string{}
-			ss.TrainEnv.Silence = false // don't add silence at start
-		}
-
-		// Sanity check
-		if ss.TrainEnv.SndTimit == false { // i.e. check if training consonant vowels
-			if ss.TrainEnv.CVsPerWord*ss.TrainEnv.CVsPerPos != len(ss.TrainEnv.CVs) {
-				fmt.Println("ERROR! - len of cv list not equal to CVsPerWord * CVsPerPos")
-			}
-		}
-
-		// always add the silence CV to the list!
-		ss.TrainEnv.CVs = append(ss.TrainEnv.CVs, "ss")
-		if len(ss.TrainEnv.CVs) >= 13 {
-			ss.TrainEnv.ThirdCVs = ss.TrainEnv.CVs[8:12]
+	// Sanity check
+	if ss.TrainEnv.SndTimit == false { // i.e. check if training consonant vowels
+		cnts := ss.TrainEnv.PosCnts()
+		if len(cnts) != ss.TrainEnv.CVsPerWord {
+			fmt.Println("ERROR! - number of syllable positions not equal to CVsPerWord")
 		}
-		if len(ss.TrainEnv.CVs) >= 9 {
-			ss.TrainEnv.SecondCVs = ss.TrainEnv.CVs[4:8]
+		n := 0
+		for _, c := range cnts {
+			n += c
 		}
-		if len(ss.TrainEnv.CVs) >= 5 {
-			ss.TrainEnv.FirstCVs = ss.TrainEnv.CVs[0:4]
+		if n != len(ss.TrainEnv.CVs) {
+			fmt.Println("ERROR! - len of cv list not equal to the sum of CVs over the syllable positions")
 		}
 	}
+
+	// always add the silence CV to the list!
+	ss.TrainEnv.CVs = append(ss.TrainEnv.CVs, "ss")
+	ss.TrainEnv.SetPosCVs()
 }
 
-// IsTestWordPart compares last/cur to see if they match a 2 syllable word in "part word" test list.
+// IsTestWordPart compares last/cur to see if they match the first 2 syllables of a word in "part word" test list.
 // An empty list is the same as a list of all possible "part words"
 // Assumes that "part wordness" has already been validated by IsPredictable() returning "Partially"
 func (ss *Sim) IsTestWordPart(last, cur string) (testWord bool) {
@@ -2909,9 +3225,9 @@ func (ss *Sim) IsTestWordPart(last, cur string) (testWord bool) {
 	}
 
 	testWord = false
-	w := last + " " + cur
 	for _, pw := range ss.TestWordsPart {
-		if w == pw {
+		cvs := strings.Fields(pw)
+		if len(cvs) >= 2 && cvs[0] == last && cvs[1] == cur {
 			testWord = true
 			break
 		}
@@ -2919,7 +3235,7 @@ func (ss *Sim) IsTestWordPart(last, cur string) (testWord bool) {
 	return
 }
 
-// IsTestWordWhole compares last/cur to see if they match a 2 syllable word in "whole word" test list.
+// IsTestWordWhole compares last/cur to see if they match the first 2 syllables of a word in "whole word" test list.
 // An empty list is the same as a list of all possible "whole words"
 // Assumes that "whole wordness" has already been validated by IsPredictable() returning "Fully"
 func (ss *Sim) IsTestWordWhole(last, cur string) (testWord bool) {
@@ -2928,9 +3244,9 @@ func (ss *Sim) IsTestWordWhole(last, cur string) (testWord bool) {
 	}
 
 	testWord = false
-	w := last + " " + cur
 	for _, ww := range ss.TestWordsWhole {
-		if w == ww {
+		cvs := strings.Fields(ww)
+		if len(cvs) >= 2 && cvs[0] == last && cvs[1] == cur {
 			testWord = true
 			break
 		}
@@ -2938,264 +3254,168 @@ func (ss *Sim) IsTestWordWhole(last, cur string) (testWord bool) {
 	return
 }
 
-// SetTestingFiles
-func (ss *Sim) SetTestingFiles(key string) {
-	// Test Test Test Test
-	ss.TestEnv.SndPath = "ccn_images/word_seg_snd_files/"
-	test := true
+// IsTestWordNon compares last/cur to see if they match the first 2 syllables of a word in "non word" test list.
+// An empty list is the same as a list of all possible "non words"
+// Assumes that "non wordness" has already been validated by SetIsPartWhole() setting "NonWord"
+func (ss *Sim) IsTestWordNon(last, cur string) (testWord bool) {
+	if len(ss.TestWordsNon) == 0 {
+		return true
+	}
 
-	switch ss.TstList {
-	// full sequence testing
-	case "Holdouts": // holdouts are a subset pulled out of training list
+	testWord = false
+	for _, nw := range ss.TestWordsNon {
+		cvs := strings.Fields(nw)
+		if len(cvs) >= 2 && cvs[0] == last && cvs[1] == cur {
+			testWord = true
+			break
+		}
+	}
+	return
+}
+
+// SetTestingFiles sets the testing environment from the stimulus set named by key
+// "Holdouts" is special - the test items are a subset pulled out of the training list
+func (ss *Sim) SetTestingFiles(key string) {
+	if key == "Holdouts" {
 		ss.TestType = SequenceTesting
+		ss.TestEnv.SndPath = ss.TrainEnv.SndPath
 		ss.TestEnv.SeqsPath = ss.TrainEnv.SeqsPath
 		ss.TestEnv.WavsPath = ss.TrainEnv.WavsPath
 		ss.TestEnv.TimesPath = ss.TrainEnv.TimesPath
+		ss.TestEnv.TimesFormat = ss.TrainEnv.TimesFormat
+		ss.TestEnv.TimesTier = ss.TrainEnv.TimesTier
+		ss.TestEnv.Words = ss.TrainEnv.Words
+		ss.TestEnv.WordsFormat = ss.TrainEnv.WordsFormat
+		ss.TestEnv.WordsTier = ss.TrainEnv.WordsTier
+		ss.TestEnv.Channel = ss.TrainEnv.Channel
+		ss.TestEnv.Mix = ss.TrainEnv.Mix
+		ss.TestEnv.SndTimit = ss.TrainEnv.SndTimit
 		ss.TestEnv.CVsPerWord = ss.TrainEnv.CVsPerWord
-		ss.TestEnv.CVsPerPos = ss.TrainEnv.CVsPerWord
+		ss.TestEnv.CVsPerPos = ss.TrainEnv.CVsPerPos
+		ss.TestEnv.CVsByPos = ss.TrainEnv.CVsByPos
+		ss.TestEnv.SndList = ""          // gets set when splitting TrainEnv.SndList
+		ss.TestEnv.CVs = ss.TrainEnv.CVs // "ss" has already been added
+		ss.TestEnv.Silence = ss.TrainEnv.Silence
+		ss.TestEnv.Continuous = ss.TrainEnv.Continuous
+		ss.TestEnv.ToneLang = ss.TrainEnv.ToneLang
+		ss.TestEnv.Synth = ss.TrainEnv.Synth
+		ss.TestEnv.Prosody = ss.TrainEnv.Prosody
+		ss.Holdout = true
 		ss.CalcPartWhole = false
 		ss.CalcBtwWthin = true
-
-	case "CVs_I":
-		ss.TestType = SequenceTesting
-		ss.TestEnv.SeqsPath = "CV_I_NoCoAr_Seqs/"
-		ss.TestEnv.WavsPath = "CV_I_NoCoAr_Wavs/"
-		ss.TestEnv.TimesPath = "CV_I_NoCoAr_Times/"
-		ss.TestEnv.CVsPerWord = 3 // The saffran, et al experiment used 3 syllable words
-		ss.TestEnv.CVsPerPos = 4  // The saffran, et al experiment had 4 cv possibilities per syllable position
-		ss.CalcPartWhole = false
-		ss.CalcBtwWthin = true
-
-	// Part word / whole word testing
-	case "CVs_I_PWWW":
-		ss.TestType = PartWholeTesting
-		ss.TestEnv.SeqsPath = "CV_I_NoCoAr_PWWW_Seqs/"
-		ss.TestEnv.WavsPath = "CV_I_NoCoAr_PWWW_Wavs/"
-		ss.TestEnv.TimesPath = "CV_I_NoCoAr_PWWW_Times/"
-		ss.TestEnv.CVsPerWord = 3 // The saffran, et al experiment used 3 syllable words
-		ss.TestEnv.CVsPerPos = 4  // The saffran, et al experiment had 4 cv possibilities per syllable position
-		ss.CalcPartWhole = true
-		ss.CalcBtwWthin = false
-
-	case "CVs_III_PWWW", "CVs_IV_PWWW", "CVs_V_PWWW", "CVs_VI_PWWW": // part-word / whole-word testing
-		ss.TestType = PartWholeTesting
-		ss.TestEnv.SeqsPath = "CV_III_IV_V_VI_NoCoAr_PWWW_Seqs/"
-		ss.TestEnv.WavsPath = "CV_III_IV_V_VI_NoCoAr_PWWW_Wavs/"
-		ss.TestEnv.TimesPath = "CV_III_IV_V_VI_NoCoAr_PWWW_Times/"
-		ss.TestEnv.CVsPerWord = 3 // The saffran, et al experiment used 3 syllable words
-		ss.TestEnv.CVsPerPos = 4  // The saffran, et al experiment had 4 cv possibilities per syllable position
-
-	case "MT_I_All_PWWW": // part-word / whole-word testing - taken from second minute of language I
-		// for graf-estes simulation
-		ss.TestType = PartWholeTesting
-		ss.TestEnv.SeqsPath = "MT_IB_PWWW_Seqs/"
-		ss.TestEnv.WavsPath = "MT_IB_PWWW_Wavs/"
-		ss.TestEnv.TimesPath = "MT_IB_PWWW_Times/"
-		ss.TestEnv.CVsPerWord = 2 // The graf-estes experiment used 2 syllable words
-		ss.TestEnv.CVsPerPos = 4  // The graf-estes experiment had 4 cv possibilities per syllable position
-
-	case "MT_IABC":
-		ss.TestType = SequenceTesting
-		ss.TestEnv.SeqsPath = "MT_IABC_Seqs/"
-		ss.TestEnv.WavsPath = "MT_IABC_Wavs/"
-		ss.TestEnv.TimesPath = "MT_IABC_Times/"
-		ss.TestEnv.CVsPerWord = 2 // The graf-estes experiment used 2 syllable words
-		ss.TestEnv.CVsPerPos = 4  // The graf-estes experiment had 4 cv possibilities per syllable position
-
-	default:
-		fmt.Println("No matching sound set for testing - ok if not testing or doing holdout train/test ")
-		test = false
-		if ss.TestRun == true {
-			os.Exit(99)
-		}
-	}
-
-	if test {
-		switch ss.TstList {
-		// full sequence testing
-		case "Holdouts":
-			ss.TestEnv.SndList = "" // gets set when splitting TrainEnv.SndList
-			ss.TestEnv.CVs = ss.TrainEnv.CVs
-			ss.Holdout = true
-			ss.TestEnv.Silence = ss.TrainEnv.Silence
-		case "CVs_I":
-			ss.TestEnv.SndList = "CV_I_NoCoAr_Test.txt"
-			ss.TestEnv.CVs = CVs_I
-
-			// Part word / whole word testing
-		case "CVs_I_PWWW":
-			ss.TestEnv.SndList = "CV_I_NoCoAr_PWWW.txt"
-			ss.TestEnv.CVs = CVs_I
-			ss.TestWordsPart = []string{"do da", "do go", "do pa", "ku da", "ku go", "ku ti", "pi go", "pi pa", "pi ti", "tu da", "tu pa", "tu ti"}
-			ss.TestWordsWhole = []string{"da ro", "go la", "pa bi", "ti bu"}
-		case "CVs_III_PWWW":
-			ss.TestEnv.SndList = "CV_III_NoCoAr_PWWW.txt"
-			ss.TestEnv.CVs = CVs_III
-			ss.TestWordsPart = []string{"hi ho", "ra pa", "di ro", "sa su", "hi pa", "ra ho", "di su", "sa ro", "hi ro", "ra su", "di ho", "sa pa"}
-			ss.TestWordsWhole = []string{"su ba", "ro lu", "pa go", "ho li"}
-		case "CVs_IV_PWWW":
-			ss.TestEnv.SndList = "CV_IV_NoCoAr_PWWW.txt"
-			ss.TestEnv.CVs = CVs_IV
-			ss.TestWordsPart = []string{"ru ki", "si hu", "ta ki", "po na", "ru hu", "si do", "ta na", "po do", "ru na", "si ki", "ta do", "po hu"}
-			ss.TestWordsWhole = []string{"do ka", "na to", "hu mo", "ki mu"}
-		case "CVs_V_PWWW":
-			ss.TestEnv.SndList = "CV_V_NoCoAr_PWWW.txt"
-			ss.TestEnv.CVs = CVs_V
-			ss.TestWordsPart = []string{"bo ma", "ga gu", "ha bi", "so bu", "bo gu", "ga ma", "ha bu", "so bi", "bo bi", "ga bu", "ha ma", "so gu"}
-			ss.TestWordsWhole = []string{"gu ri", "ma gi", "bi tu", "bu ni"}
-		case "CVs_VI_PWWW":
-			ss.TestEnv.SndList = "CV_VI_NoCoAr_PWWW.txt"
-			ss.TestEnv.CVs = CVs_VI
-			ss.TestWordsPart = []string{"mi lo", "pu nu", "ko ti", "la da", "mi nu", "pu da", "ko lo", "la ti", "mi ti", "pu lo", "ko da", "la nu"}
-			ss.TestWordsWhole = []string{"da ku", "ti no", "nu pi", "lo du"}
-		case "MT_I_PWWW":
-			ss.TestEnv.SndList = "MT_IB_PWWW_List.txt"
-			ss.TestEnv.CVs = []string{"ti", "do", "ga", "mo", "may", "bu", "pi", "ku"}
-			ss.TestWordsPart = []string{"pi mo", "ku ga", "bu ga", "ku do", "may mo", "pi ti", "bu mo", "ku ti", "may ga", "pi do"}
-			ss.TestWordsWhole = []string{"mo ku", "ga pi", "do bu", "ti may"}
-		case "MT_IABC":
-			ss.TestEnv.SndList = "MT_IABC_Test.txt"
-			ss.TestEnv.CVs = CVs_MT
-			ss.TestEnv.Silence = true
-			ss.TestWordsPart = []string{"ku ga", "pi mo"}
-			ss.TestWordsWhole = []string{"do bu", "ti may"}
-
-		default:
-			log.Println("No matching case for test sndfiles switch")
-		}
-
-		// add the silence CV to the list!
-		if ss.Holdout == false { // if true test = train so "ss" has already been added
-			ss.TestEnv.CVs = append(ss.TestEnv.CVs, "ss")
-		}
-
-		// always set these
-		if len(ss.TrainEnv.CVs) >= 13 {
-			ss.TestEnv.ThirdCVs = ss.TrainEnv.CVs[8:12]
-		}
-		if len(ss.TrainEnv.CVs) >= 9 {
-			ss.TestEnv.SecondCVs = ss.TrainEnv.CVs[4:8]
+	} else {
+		st, err := ss.StimSets.ByNameTry(key)
+		if err != nil {
+			fmt.Println("No matching sound set for testing - ok if not testing or doing holdout train/test ")
+			if ss.TestRun == true {
+				os.Exit(99)
+			}
+			return
 		}
-		if len(ss.TrainEnv.CVs) >= 5 {
-			ss.TestEnv.FirstCVs = ss.TrainEnv.CVs[0:4]
+		ss.TestEnv.SetStimSet(st)
+		if st.TestSndList != "" {
+			ss.TestEnv.SndList = st.TestSndList
+		} else if strings.HasSuffix(st.SndList, "_Train.txt") {
+			fmt.Printf("Warning: stimulus set %v has no TestSndList, testing on its training list %v\n", st.Name, st.SndList)
 		}
+		ss.SetTestType(st)
+		// add the silence CV to the list!
+		ss.TestEnv.CVs = append(ss.TestEnv.CVs, "ss")
 	}
+	// always set these from the training CVs
+	ss.TestEnv.PosCVs = ss.TrainEnv.PosCVs
+	ss.TestEnv.DepDist = ss.TrainEnv.DepDist
+	ss.TestEnv.DepFrames = ss.TrainEnv.DepFrames
 }
 
-// SetTrainingFiles
-func (ss *Sim) SetPretrainingFiles(key string) {
-	pretrain := true
-
-	switch ss.PreTrnList {
-	case "TIMIT_ALL_SX_F":
-		ss.PreTrainEnv.SndPath = "ccn_images/sound/timit/"
-		ss.PreTrainEnv.WavsPath = "TIMIT/TRAIN/"
-		ss.PreTrainEnv.SeqsPath = "TIMIT/TRAIN/"
-		ss.PreTrainEnv.TimesPath = "TIMIT/TRAIN/"
-		ss.PreTrainEnv.SndTimit = true
+// SetTestType sets the type of testing, which stats to calculate and the test words from the stimulus set
+func (ss *Sim) SetTestType(st *StimSet) {
+	ss.TestType = st.TestType
+	ss.CalcDeps = false
+	if ss.TestType == PartWholeTesting {
+		ss.CalcPartWhole = true
+		ss.CalcBtwWthin = false
+	} else if ss.TestType == DependencyTesting {
+		ss.CalcPartWhole = false
+		ss.CalcBtwWthin = false
+		ss.CalcDeps = true
+	} else {
 		ss.CalcPartWhole = false
 		ss.CalcBtwWthin = true
-		ss.SaveActs = false
+	}
+	if len(st.TestWordsPart) > 0 || len(st.TestWordsWhole) > 0 || len(st.TestWordsNon) > 0 {
+		ss.TestWordsPart = st.TestWordsPart
+		ss.TestWordsWhole = st.TestWordsWhole
+		ss.TestWordsNon = st.TestWordsNon
+	}
+}
 
-	default:
+// SetPretrainingFiles sets the pretraining environment from the stimulus set named by key
+func (ss *Sim) SetPretrainingFiles(key string) {
+	st, err := ss.StimSets.ByNameTry(key)
+	if err != nil {
 		fmt.Println("No matching sound set for pretraining - ok if doing train or test")
-		pretrain = false
+		return
 	}
-
-	if pretrain {
-		switch ss.PreTrnList {
-		case "TIMIT_ALL_SX_F":
-			ss.PreTrainEnv.SndList = "trainAllFemaleSX.txt"
-			ss.PreTrainEnv.CVs = []string{}
-			ss.PreTrainEnv.Silence = false // don't add silence at start		}
-		}
+	ss.PreTrainEnv.SetStimSet(st)
+	if st.Timit {
+		ss.CalcPartWhole = false
+		ss.CalcBtwWthin = true
+		ss.SaveActs = false
 	}
 }
 
-// SetTestingFiles
+// SetPretestingFiles sets the pretesting environment from the stimulus set named by key
+// "Holdouts" is special - the test items are a subset pulled out of the training list
 func (ss *Sim) SetPretestingFiles(key string) {
-	// Test Test Test Test
-	ss.PreTestEnv.SndPath = "ccn_images/word_seg_snd_files/"
-	test := true
-
-	switch ss.PreTstList {
-	// full sequence testing
-	case "Holdouts": // holdouts are a subset pulled out of training list
+	if key == "Holdouts" {
 		ss.TestType = SequenceTesting
+		ss.PreTestEnv.SndPath = ss.TrainEnv.SndPath
 		ss.PreTestEnv.SeqsPath = ss.TrainEnv.SeqsPath
 		ss.PreTestEnv.WavsPath = ss.TrainEnv.WavsPath
 		ss.PreTestEnv.TimesPath = ss.TrainEnv.TimesPath
+		ss.PreTestEnv.TimesFormat = ss.TrainEnv.TimesFormat
+		ss.PreTestEnv.TimesTier = ss.TrainEnv.TimesTier
+		ss.PreTestEnv.Words = ss.TrainEnv.Words
+		ss.PreTestEnv.WordsFormat = ss.TrainEnv.WordsFormat
+		ss.PreTestEnv.WordsTier = ss.TrainEnv.WordsTier
+		ss.PreTestEnv.Channel = ss.TrainEnv.Channel
+		ss.PreTestEnv.Mix = ss.TrainEnv.Mix
+		ss.PreTestEnv.SndTimit = ss.TrainEnv.SndTimit
 		ss.PreTestEnv.CVsPerWord = ss.TrainEnv.CVsPerWord
 		ss.PreTestEnv.CVsPerPos = ss.TrainEnv.CVsPerPos
+		ss.PreTestEnv.CVsByPos = ss.TrainEnv.CVsByPos
+		ss.PreTestEnv.SndList = ""          // gets set when splitting TrainEnv.SndList
+		ss.PreTestEnv.CVs = ss.TrainEnv.CVs // "ss" has already been added
+		ss.PreTestEnv.Silence = ss.TrainEnv.Silence
+		ss.PreTestEnv.Continuous = ss.TrainEnv.Continuous
+		ss.PreTestEnv.ToneLang = ss.TrainEnv.ToneLang
+		ss.PreTestEnv.Synth = ss.TrainEnv.Synth
+		ss.PreTestEnv.Prosody = ss.TrainEnv.Prosody
+		ss.Holdout = true
 		ss.CalcPartWhole = false
 		ss.CalcBtwWthin = true
-
-	case "CVs_I":
-		// for saffran simulation
-		ss.TestType = SequenceTesting
-		ss.PreTestEnv.SeqsPath = "CV_I_NoCoAr_Seqs/"
-		ss.PreTestEnv.WavsPath = "CV_I_NoCoAr_Wavs/"
-		ss.PreTestEnv.TimesPath = "CV_I_NoCoAr_Times/"
-		ss.PreTestEnv.CVsPerWord = 3 // The saffran, et al experiment used 3 syllable words
-		ss.PreTestEnv.CVsPerPos = 4  // The saffran, et al experiment had 4 cv possibilities per syllable position
-		ss.CalcPartWhole = false
-		ss.CalcBtwWthin = true
-
-	case "MT_IABC":
-		// for graf-estes simulation
-		ss.TestType = SequenceTesting
-		ss.PreTestEnv.SeqsPath = "MT_IABC_Seqs/"
-		ss.PreTestEnv.WavsPath = "MT_IABC_Wavs/"
-		ss.PreTestEnv.TimesPath = "MT_IABC_Times/"
-		ss.PreTestEnv.CVsPerWord = 2 // The graf-estes experiment used 2 syllable words
-		ss.PreTestEnv.CVsPerPos = 4  // The graf-estes experiment had 4 cv possibilities per syllable position
-
-	default:
-		fmt.Println("No matching sound set for testing - ok if not testing or doing holdout train/test ")
-		test = false
-		if ss.TestRun == true {
-			os.Exit(99)
+	} else {
+		st, err := ss.StimSets.ByNameTry(key)
+		if err != nil {
+			fmt.Println("No matching sound set for pretesting - ok if not testing or doing holdout train/test ")
+			if ss.TestRun == true {
+				os.Exit(99)
+			}
+			return
 		}
-	}
-
-	if test {
-		switch ss.PreTstList {
-		// full sequence testing
-		case "Holdouts":
-			ss.PreTestEnv.SndList = "" // gets set when splitting TrainEnv.SndList
-			ss.PreTestEnv.CVs = ss.TrainEnv.CVs
-			ss.Holdout = true
-			ss.TestEnv.Silence = ss.TrainEnv.Silence
-
-		case "CVs_I":
-			ss.PreTestEnv.SndList = "CV_I_NoCoAr_Test.txt"
-			ss.PreTestEnv.CVs = CVs_I
-
-		case "MT_IABC":
-			ss.PreTestEnv.SndList = "MT_IABC_Test.txt"
-			ss.PreTestEnv.CVs = CVs_MT
-			ss.PreTestEnv.Silence = true
-			ss.TestWordsPart = []string{"ku ga", "pi mo"}
-			ss.TestWordsWhole = []string{"do bu", "ti may"}
-
-		default:
-			log.Println("No matching case for pretest sndfiles switch")
+		ss.PreTestEnv.SetStimSet(st)
+		if st.TestSndList != "" {
+			ss.PreTestEnv.SndList = st.TestSndList
+		} else if strings.HasSuffix(st.SndList, "_Train.txt") {
+			fmt.Printf("Warning: stimulus set %v has no TestSndList, testing on its training list %v\n", st.Name, st.SndList)
 		}
-
+		ss.SetTestType(st)
 		// add the silence CV to the list!
-		if ss.Holdout == false { // if true test = train so "ss" has already been added
-			ss.PreTestEnv.CVs = append(ss.PreTestEnv.CVs, "ss")
-		}
-
-		// always set these
-		if len(ss.TrainEnv.CVs) >= 13 {
-			ss.PreTestEnv.ThirdCVs = ss.TrainEnv.CVs[8:12]
-		}
-		if len(ss.TrainEnv.CVs) >= 9 {
-			ss.PreTestEnv.SecondCVs = ss.TrainEnv.CVs[4:8]
-		}
-		if len(ss.TrainEnv.CVs) >= 5 {
-			ss.PreTestEnv.FirstCVs = ss.TrainEnv.CVs[0:4]
-		}
+		ss.PreTestEnv.CVs = append(ss.PreTestEnv.CVs, "ss")
 	}
+	// always set these from the training CVs
+	ss.PreTestEnv.PosCVs = ss.TrainEnv.PosCVs
+	ss.PreTestEnv.DepDist = ss.TrainEnv.DepDist
+	ss.PreTestEnv.DepFrames = ss.TrainEnv.DepFrames
 }
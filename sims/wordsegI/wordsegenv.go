@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math"
 	"math/rand"
@@ -12,11 +13,13 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/emer/auditory/agabor"
+	"github.com/ccnlab/statlearn/synth"
 	"github.com/emer/emergent/env"
+	"github.com/emer/emergent/params"
 	"github.com/emer/empi/mpi"
 	"github.com/emer/etable/etable"
 	"github.com/emer/etable/etensor"
+	"github.com/go-audio/audio"
 	"github.com/goki/gi/gi"
 	"github.com/goki/ki/kit"
 )
@@ -59,6 +62,7 @@ const (
 	NotPartNorWhole PartWhole = iota
 	PartWord
 	WholeWord
+	NonWord // a CV order that was never in the training, e.g. the first CVs of 2 words - see saffran 1996 experiment 1
 	PartWholeN
 )
 
@@ -80,6 +84,37 @@ const (
 	PredictableN
 )
 
+// Dependency
+type Dependency int32
+
+//go:generate stringer -type=Dependency
+
+// Describes the nonadjacent dependency of a CV on the CV DepDist before it (e.g. C of an AxC frame)
+var KiT_Dependency = kit.Enums.AddEnum(DependencyN, kit.NotBitFlag, nil)
+
+const (
+	NoDependency  Dependency = iota // not in the dependent position of a frame
+	TrainedFrame                    // the CV completes a frame that was in the training
+	ViolatedFrame                   // the CV is the end of a frame but not the one started DepDist CVs before
+	DependencyN
+)
+
+// WordPos
+type WordPos int32
+
+//go:generate stringer -type=WordPos
+
+// Describes the position of a CV (or phone) within its word, from the word labels of natural speech
+var KiT_WordPos = kit.Enums.AddEnum(WordPosN, kit.NotBitFlag, nil)
+
+const (
+	NoWordPos   WordPos = iota // not within a word, e.g. silence, or no word labels
+	WordInitial                // the first CV of a word, including words of only one CV
+	WordMedial                 // neither the first nor the last CV of a word
+	WordFinal                  // the last CV of a word
+	WordPosN
+)
+
 ////////////////////////////////////////////////////////////////////////////////////////////
 // Environment - params and config for the train/test environment
 
@@ -91,6 +126,13 @@ type CVCurrent struct {
 	Cur         string            `desc:"consonant vowel (CV) of current segment"`
 	Predictable Predictable       `desc:"is this CV fully or partially predictable"`
 	Word        PartWhole         `desc:"was this CV fully predictable during training (wholeword)"`
+	FwdTP       float64           `desc:"forward transitional probability, P(Cur | Last), of the training sequences"`
+	BwdTP       float64           `desc:"backward transitional probability, P(Last | Cur), of the training sequences"`
+	BiFreq      float64           `desc:"frequency of the Last Cur bigram in the training sequences"`
+	Hist        []string          `desc:"the last DepDist + 1 CVs of the sequence, not including silence, the current CV is last"`
+	DepDist     int               `desc:"distance back to the CV that starts the nonadjacent frame this CV is the dependent position of, 0 if not in a dependent position"`
+	Dep         Dependency        `desc:"is this CV the end of a trained or a violated nonadjacent frame"`
+	WordPos     WordPos           `desc:"position of the CV within its word, from the word labels of natural speech"`
 	Predicted   map[string]string `view:"no-inline" desc:"layer name is key and predicted CV is value, for cases where the CV is fully predicatable, "`
 }
 
@@ -102,6 +144,13 @@ func (cv *CVCurrent) Reset() {
 	cv.Ordinal = -1
 	cv.Predictable = Ignore
 	cv.Word = NotPartNorWhole
+	cv.FwdTP = 0
+	cv.BwdTP = 0
+	cv.BiFreq = 0
+	cv.Hist = cv.Hist[:0]
+	cv.DepDist = 0
+	cv.Dep = NoDependency
+	cv.WordPos = NoWordPos
 }
 
 // CVSegment
@@ -123,43 +172,59 @@ type CVTime struct {
 	End        float64 `desc:"end time of this CV in a particular sequence in milliseconds"`
 	StartAlpha float64 `desc:"start time of this CV in a particular sequence in milliseconds, adjusted for random start silence and aligned to alpha (100ms)"`
 	EndAlpha   float64 `desc:"end time of this CV in a particular sequence in milliseconds, adjusted for random start silence and aligned to alpha (100ms)"`
+	WordPos    WordPos `desc:"position of this CV within its word, set from the word labels if there are any"`
 }
 type WEEnv struct {
 	// the environment has the training/test data and the procedures for creating/choosing the input to the model
 	// "Segment" in var name indicates that the data or value only applies to a segment of samples rather than the entire signal
-	Nm         string          `desc:"name of this environment"`
-	Dsc        string          `desc:"description of this environment"`
-	Run        env.Ctr         `view:"inline" desc:"current run of model as provided during Init"`
-	Epoch      env.Ctr         `view:"inline" desc:"number of times through a set of sequences"`
-	Sequence   env.Ctr         `view:"inline" desc:"current sequence which is a series of trials (segments of sound in this simulation"`
-	Trial      env.Ctr         `view:"inline" desc:"current trial which is 2 or more events"`
-	Event      env.Ctr         `view:"inline" desc:"the current event of the trial"`
-	TrialName  string          `desc:"if Table has a Name column, this is the contents of that for current trial"`
-	SeqOrder   SeqOrder        `view:"+" desc:"order of sound sequences - ordered, random, cyclical"`
-	Patterns   *etable.IdxView `desc:"this is a one row table with the set of patterns to output for the next event"`
-	SndCur     string          `view:"+" desc:" name of current open sound file"`
-	SeqCur     string          `view:"+" desc: identifier (filename) of currently loaded sound"`
-	SndList    string          `view:"-" desc:" stash file name for reload"`
-	SndFiles   []string        `view:"no-inline" desc:" the list of sound files"`
-	SndTimit   bool            `view:"-" desc:" are the sound files timit files"`
-	SndPath    string          `view:"-" desc:" base path to all sound, sequence and timing files"`
-	SeqsPath   string          `desc:"path to the human readable files of the sound sequences"`
-	WavsPath   string          `desc:"path to wav files"`
-	TimesPath  string          `desc:"path to the timing information for wav files - also called labels"`
-	SndShort   SndEnv          `view:"+" desc:" sound processing values and matrices for the short duration pathway"`
-	SndLong    SndEnv          `view:"+" desc:" sound processing values and matrices for the long duration pathway"`
-	MaxSegCnt  int             `desc:"this will be the minimum segment count of SndShort and SndLong (or others if there are more)"`
-	CV         CVCurrent       `desc:"struct containing segment/CV state"`
-	CVs        []string        `desc:"the full list of CVs in the training"`
-	CVTimes    []CVTime        `desc:"a slice of all of the CVs and their start/end times for the currently loaded sequence of CVs"`
-	CVsPerWord int             `desc:"how many CVs per word"`
-	CVsPerPos  int             `desc:"how many CV possibilities per syllable position - assumes same for each position"`
-	FirstCVs   []string        `desc:"the CVs in the first position of the trisyllabic words"`  // order is important
-	SecondCVs  []string        `desc:"the CVs in the second position of the trisyllabic words"` // order is important
-	ThirdCVs   []string        `desc:"the CVs in the third position of the trisyllabic words"`  // order is important
-	Silence    bool            `desc:"add random period of silence at start of sequence"`
-	SilenceMax int             `desc:"maximum milliseconds of silence to add at start of sequence - uniform random"`
-	HoldoutPct int             `desc:"percentage of items to holdout for testing"`
+	Nm          string          `desc:"name of this environment"`
+	Dsc         string          `desc:"description of this environment"`
+	Run         env.Ctr         `view:"inline" desc:"current run of model as provided during Init"`
+	Epoch       env.Ctr         `view:"inline" desc:"number of times through a set of sequences"`
+	Sequence    env.Ctr         `view:"inline" desc:"current sequence which is a series of trials (segments of sound in this simulation"`
+	Trial       env.Ctr         `view:"inline" desc:"current trial which is 2 or more events"`
+	Event       env.Ctr         `view:"inline" desc:"the current event of the trial"`
+	TrialName   string          `desc:"if Table has a Name column, this is the contents of that for current trial"`
+	SeqOrder    SeqOrder        `view:"+" desc:"order of sound sequences - ordered, random, cyclical"`
+	Patterns    *etable.IdxView `desc:"this is a one row table with the set of patterns to output for the next event"`
+	SndCur      string          `view:"+" desc:" name of current open sound file"`
+	SeqCur      string          `view:"+" desc: identifier (filename) of currently loaded sound"`
+	SndList     string          `view:"-" desc:" stash file name for reload"`
+	SndFiles    []string        `view:"no-inline" desc:" the list of sound files"`
+	SndTimit    bool            `view:"-" desc:" are the sound files timit files"`
+	SndPath     string          `view:"-" desc:" base path to all sound, sequence and timing files"`
+	SeqsPath    string          `desc:"path to the human readable files of the sound sequences"`
+	WavsPath    string          `desc:"path to wav files"`
+	TimesPath   string          `desc:"path to the timing information for wav files - also called labels"`
+	TimesFormat LabelFormat     `desc:"format of the label files in TimesPath"`
+	TimesTier   string          `desc:"name of the tier to read for label formats with multiple tiers, e.g. TextGrid, empty for the first"`
+	Words       bool            `desc:"load the word labels of the sound files, in TimesPath, to mark the word boundaries of natural speech"`
+	WordsFormat LabelFormat     `desc:"format of the word label files -- DefaultLabels is TIMIT .WRD files if timit, otherwise TimesFormat"`
+	WordsTier   string          `desc:"name of the word tier for label formats with multiple tiers, e.g. words of a TextGrid"`
+	Channel     int             `desc:"channel of multi-channel sound files to use, -1 for all of the channels, e.g. one for each ear"`
+	Mix         bool            `desc:"mix the channels of multi-channel sound files into one, rather than selecting Channel"`
+	SampleRate  int             `desc:"canonical sample rate every sound is resampled to when loaded, so the window, step and filter geometry is the same for all corpora -- 0 keeps the rate of each sound"`
+	FrontEnd    FrontEndType    `desc:"the auditory preprocessing of the pathways"`
+	Norm        NormType        `desc:"the normalization of the log filterbank output of the pathways -- the fixed renormalization range, or per-utterance, running or automatic gain control normalization of each band"`
+	Paths       Pathways        `desc:"the auditory pathways, each a window onto the sound at one timescale that is applied to an input layer"`
+	Snds        []*SndEnv       `view:"+" desc:" sound processing values and matrices for each pathway, in the order of Paths"`
+	MaxSegCnt   int             `desc:"this will be the minimum segment count of the pathways"`
+	CV          CVCurrent       `desc:"struct containing segment/CV state"`
+	CVs         []string        `desc:"the full list of CVs in the training"`
+	CVTimes     []CVTime        `desc:"a slice of all of the CVs and their start/end times for the currently loaded sequence of CVs"`
+	CVsPerWord  int             `desc:"how many CVs per word"`
+	CVsPerPos   int             `desc:"how many CV possibilities per syllable position - used when CVsByPos is empty"`
+	CVsByPos    []int           `desc:"how many CV possibilities in each syllable position, if the positions differ - empty means CVsPerPos for every position"`
+	PosCVs      [][]string      `desc:"the CVs in each syllable position of the words, PosCVs[0] being the word initial CVs -- which CVs of adjacent positions go together in a word is taken from the training bigrams, see IsInWord and SetIsPredictable"` // order is important
+	DepDist     int             `desc:"distance of the nonadjacent dependencies of the language, e.g. 2 for AxC frames, 0 if none"`
+	DepFrames   []CVPair        `desc:"the trained nonadjacent frames - Last is the CV that starts the frame and Cur the CV DepDist later"`
+	Silence     bool            `desc:"add random period of silence at start of sequence"`
+	Continuous  bool            `desc:"the sound files are one continuous stream -- each file is trimmed to its labelled CVs and joined to the end of the last with no silence, and the CV state is carried across the join rather than reset"`
+	SilenceMax  int             `desc:"maximum milliseconds of silence to add at start of sequence - uniform random"`
+	HoldoutPct  int             `desc:"percentage of items to holdout for testing"`
+	ToneLang    *ToneLang       `desc:"if set, sequences are pure tones rendered directly into the signal, no wav or label files"`
+	Synth       *synth.Synth    `desc:"if set, sequences are syllables rendered by the formant synthesizer directly into the signal, no wav or label files"`
+	Prosody     *Prosody        `desc:"if set, prosodic cues (stress, lengthening, pauses) are added to the syllables of each sound after it is loaded"`
 
 	// specific to word break detection
 	//PW       PartWhole `desc:" is the current segment beginning of part word"`
@@ -171,6 +236,16 @@ type WEEnv struct {
 	SndIdx       int         `view:"-" desc:"the index into the soundlist of the sound from last trial"`
 	BinThr       float32     `def:"0.4" desc:"threshold for binarizing"`
 	msSilence    float64     `desc:"add this much random silence at front of signal"`
+
+	Augment   Augment         `view:"inline" desc:"random pitch, tempo, level and noise augmentation of each sound as it is loaded"`
+	FeatCache FeatCache       `view:"inline" desc:"on-disk cache of the processed segments of each sound file, so later epochs and runs read the features rather than processing the sound again"`
+	Prefetch  bool            `desc:"draw the next sound and load and process it in the background while the network runs the current sound -- the sounds are drawn from the random source of the env, so the sequence is the same as without prefetching -- continuous and rendered sounds are not prefetched"`
+	Sheets    []*params.Sheet `view:"-" desc:"the env param sheets, e.g. TrainEnv, of the Base and current param sets -- see SetParams"`
+	Rand      *rand.Rand      `view:"-" desc:"random source for the order of the sounds and their silence, seeded for each run by InitRand, so that the draws do not depend on when they are made"`
+
+	pf       *Prefetch `view:"-" desc:"the next sound, being loaded in the background"`
+	spare    []*SndEnv `view:"-" desc:"the pathways the next sound is loaded into, swapped with Snds when the sound is used"`
+	chWarned bool      `view:"-" desc:"a channel past the channels of the sound has been warned about, see CheckChannel"`
 }
 
 func (we *WEEnv) DefaultsTrn() {
@@ -184,6 +259,9 @@ func (we *WEEnv) DefaultsTrn() {
 	we.Silence = true
 	we.SilenceMax = 25.0
 	we.HoldoutPct = 17
+	we.Augment.Defaults()
+	we.FeatCache.Defaults()
+	we.SetPathways(DefaultPathways())
 }
 
 func (we *WEEnv) DefaultsTest() {
@@ -196,110 +274,186 @@ func (we *WEEnv) DefaultsTest() {
 	we.Silence = true
 	we.HoldoutPct = 0
 	we.SilenceMax = 25.0
+	we.Augment.Defaults()
+	we.FeatCache.Defaults()
+	we.SetPathways(DefaultPathways())
 }
 
-func (we *WEEnv) InitSndShort() {
-	we.MoreSegments = true
-	we.SndShort.Defaults()
-
-	we.SndShort.Nm = "SoundShort"
-	we.SndShort.Dsc = "150 ms window onto sound"
-
-	// these should match the number of neuron pools in the input layer
-	we.SndShort.GborPoolsY = 12
-	we.SndShort.GborPoolsX = 6
-
-	// override defaults
-	we.SndShort.Params.SegmentMs = 150
-	we.SndShort.Params.WinMs = 25
-	we.SndShort.Params.StepMs = 10
-	we.SndShort.Params.StrideMs = 100
-	we.SndShort.Params.BorderSteps = 5
-
-	// for example, with Stride/StepMs equal to 10 and 3 border steps on either side there will be 16 values for the gabor stepping to cover
-	// so the gbor (size of 6) will go from 0-5, 2-7, 4-9 ... 10-15
-
-	// these overrides must follow Mel.Defaults
-	we.SndShort.Mel.FBank.RenormMin = 2 // 2/9 better than 0/10
-	we.SndShort.Mel.FBank.RenormMax = 9
-	we.SndShort.Mel.FBank.LoHz = 20
-	we.SndShort.Mel.FBank.HiHz = 6000
-
-	g := new(agabor.Params)
-	g.Defaults()
-	g.TimeSize = 10
-	g.TimeStride = 3
-	g.FreqSize = 6
-	g.FreqStride = 3
-	g.WaveLen = 6.0
-	g.HorizSigmaWidth = 0.2
+// SetPathways sets the pathways and makes the sound env of each
+func (we *WEEnv) SetPathways(paths Pathways) {
+	we.StopPrefetch()
+	we.Paths = paths
+	we.Snds = make([]*SndEnv, len(paths))
+	we.spare = make([]*SndEnv, len(paths))
+	for i := range paths {
+		we.Snds[i] = &SndEnv{}
+		we.spare[i] = &SndEnv{}
+	}
+}
 
-	st := -1.0
-	end := -1.0
-	if we.SndTimit {
-		st = we.CVTimes[0].Start * 1000
-		end = we.CVTimes[len(we.CVTimes)-1].End * 1000
+// SetParams applies the param sheets to the env, and keeps them to apply to the sound env of each pathway
+// when it is initialized for a sound, after the sound params are reset. The params of a Sel all target
+// either the WEEnv, e.g. "WEEnv.SilenceMax", or the SndEnv, e.g. "SndEnv.Gbor.TimeStride" -- Sel "SndEnv"
+// applies to every pathway and the name of a pathway, e.g. "#SoundShort", to just that pathway.
+func (we *WEEnv) SetParams(sheets []*params.Sheet, setMsg bool) {
+	we.StopPrefetch() // the next sound was processed with the old params
+	we.Sheets = sheets
+	for _, sh := range sheets {
+		sh.Apply(we, setMsg)
+		for _, se := range we.Snds { // reports the sound params, which are applied again for each sound
+			sh.Apply(se, setMsg)
+		}
 	}
-	err, _ := we.SndShort.Init(*g, we.msSilence, st, end)
-	if err != nil {
-		fmt.Println("Error returned from NewSoundInit")
+}
+
+// SetSndParams sets the sample rate and which channels of the sound files the pathways load,
+// which hold for every sound of the env and SndEnv.Defaults does not reset
+func (we *WEEnv) SetSndParams() {
+	for _, se := range we.Snds {
+		se.Params.SampleRate = we.SampleRate
+		se.Params.Channel = we.Channel
+		se.Params.Mix = we.Mix
 	}
 }
 
-func (we *WEEnv) InitSndLong() {
-	we.MoreSegments = true
-	we.SndLong.Defaults()
-
-	we.SndLong.Nm = "SoundLong"
-	we.SndLong.Dsc = "200 ms window onto sound"
-
-	// these should match the number of neuron pools in the input layer
-	we.SndLong.GborPoolsY = 12
-	we.SndLong.GborPoolsX = 6
-
-	// override defaults
-	we.SndLong.Params.SegmentMs = 150
-	we.SndLong.Params.WinMs = 25
-	we.SndLong.Params.StepMs = 10
-	we.SndLong.Params.StrideMs = 100
-	we.SndLong.Params.BorderSteps = 6
-	// with SegmentMs/StepMs equal to 30 and 6 border steps on either side there will be 42 values for the gabor stepping to cover
-	// so the gbor (size of 8) will go from 0-7, 3-10, 6-13 ... 33-40
-	// for a time size of 10 the border steps needs to go to 7, etc.
-
-	// these overrides must follow Mel.Defaults
-	we.SndLong.Mel.FBank.RenormMin = 2 // 2/9 better than 0/10
-	we.SndLong.Mel.FBank.RenormMax = 9
-	we.SndLong.Mel.FBank.LoHz = 20
-	we.SndLong.Mel.FBank.HiHz = 6000
-
-	g := new(agabor.Params)
-	g.Defaults()
-	g.TimeSize = 6
-	g.TimeStride = 4
-	g.FreqSize = 6
-	g.FreqStride = 3
-	g.WaveLen = 6.0
-	g.HorizSigmaWidth = 0.2
+// SetStimSet sets the paths and CV information of the env from the stimulus set
+func (we *WEEnv) SetStimSet(st *StimSet) {
+	we.StopPrefetch()
+	we.SndPath = st.SndPath
+	we.SeqsPath = st.SeqsPath
+	we.WavsPath = st.WavsPath
+	we.TimesPath = st.TimesPath
+	we.TimesFormat = st.TimesFormat
+	we.TimesTier = st.TimesTier
+	we.Words = st.Words
+	we.WordsFormat = st.WordsFormat
+	we.WordsTier = st.WordsTier
+	we.Channel = st.Channel
+	we.Mix = st.Mix
+	we.SndList = st.SndList
+	we.SndTimit = st.Timit
+	we.Silence = st.Silence
+	we.ToneLang = st.ToneLang
+	we.Synth = st.Synth
+	we.CVs = append([]string{}, st.CVs...) // copy - "ss" gets added to the env list
+	if st.CVsPerWord > 0 {
+		we.CVsPerWord = st.CVsPerWord
+		we.CVsPerPos = st.CVsPerPos
+	}
+	we.CVsByPos = st.CVsByPos
+	we.Continuous = st.Continuous
+	we.Prosody = st.Prosody
+	if we.Prosody != nil {
+		we.Prosody.CVsPerWord = we.CVsPerWord
+	}
+	we.DepDist = st.DepDist
+	we.DepFrames = nil
+	for _, fr := range st.DepFrames {
+		cvs := strings.Fields(fr)
+		if len(cvs) != 2 {
+			fmt.Println("SetStimSet: frame should be 2 CVs separated by a space:", fr)
+			continue
+		}
+		we.DepFrames = append(we.DepFrames, CVPair{cvs[0], cvs[1]})
+	}
+}
+
+// PosCnts returns the number of CV possibilities for each of the CVsPerWord syllable positions
+func (we *WEEnv) PosCnts() []int {
+	if len(we.CVsByPos) > 0 {
+		return we.CVsByPos
+	}
+	cnts := make([]int, we.CVsPerWord)
+	for i := range cnts {
+		cnts[i] = we.CVsPerPos
+	}
+	return cnts
+}
 
+// SetPosCVs splits the full list of CVs, which is grouped by syllable position, into PosCVs.
+// Any CVs beyond the positional ones (e.g. silence) are not included.
+func (we *WEEnv) SetPosCVs() {
+	we.PosCVs = nil
+	st := 0
+	for _, n := range we.PosCnts() {
+		if st+n > len(we.CVs) {
+			break
+		}
+		we.PosCVs = append(we.PosCVs, we.CVs[st:st+n])
+		st += n
+	}
+}
+
+// PosCVIdx returns the index of the CV in the given syllable position, -1 if not there
+func (we *WEEnv) PosCVIdx(pos int, cv string) int {
+	if pos < 0 || pos >= len(we.PosCVs) {
+		return -1
+	}
+	for i, pcv := range we.PosCVs[pos] {
+		if pcv == cv {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsPosCV returns true if the CV is in any of the syllable positions
+func (we *WEEnv) IsPosCV(cv string) bool {
+	for pos := range we.PosCVs {
+		if we.PosCVIdx(pos, cv) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInWord returns true if cur follows last within a word of the training sequences, with last in syllable
+// position pos -- i.e. the two CVs are in adjacent positions and the training bigrams, tp, include the pair.
+// Without training bigrams, e.g. for timit, the two positions must have the same number of CVs and
+// the CVs must come from the same word.
+func (we *WEEnv) IsInWord(tp *TransProbs, pos int, last, cur string) bool {
+	li := we.PosCVIdx(pos, last)
+	ci := we.PosCVIdx(pos+1, cur)
+	if li < 0 || ci < 0 {
+		return false
+	}
+	if tp != nil && tp.NBigrams > 0 {
+		return tp.Fwd(last, cur) > 0
+	}
+	return len(we.PosCVs[pos]) == len(we.PosCVs[pos+1]) && li == ci
+}
+
+// InitSnds initializes the sound env of each pathway for the sound just loaded, from the pathway params
+func (we *WEEnv) InitSnds() {
+	we.MoreSegments = true
 	st := -1.0
 	end := -1.0
-	if we.SndTimit {
+	if we.SndTimit || (we.Continuous && !we.IsRendered()) {
 		st = we.CVTimes[0].Start * 1000
 		end = we.CVTimes[len(we.CVTimes)-1].End * 1000
 	}
-	err, _ := we.SndLong.Init(*g, we.msSilence, st, end)
-	if err != nil {
-		fmt.Println("Error returned from NewSoundInit")
+	for i, pw := range we.Paths {
+		se := we.Snds[i]
+		se.Defaults()
+		pw.Config(se)
+		se.Sheets = we.Sheets
+		se.Continuous = we.Continuous
+		se.FrontEnd = we.FrontEnd
+		se.Norm.Type = we.Norm
+		err, _ := se.Init(pw.Gabor.Params(), we.msSilence, st, end)
+		if err != nil {
+			fmt.Println("Error returned from NewSoundInit")
+		}
+		we.OpenCache(se, st, end)
 	}
 }
 
 // SetIsPredictable checks to if the first segment of the CV is one that is "fully" predictable
 // (i.e. within an unchanging word)
-// or partially predictable (i.e. one of multiple that are possible)
-func (we *WEEnv) SetIsPredictable() {
+// or partially predictable (i.e. one of multiple that are possible) -- tp are the bigrams of the training sequences
+func (we *WEEnv) SetIsPredictable(tp *TransProbs) {
 	we.CV.Predictable = Ignore
-	if we.Nm == "PreTrainEnv" { // no predicting when just pretraining
+	if we.Nm == "PreTrainEnv" && !we.Words { // no predicting when just pretraining, unless there are word boundaries
 		return
 	}
 	if we.CV.Ordinal == 0 { // ignore first CV - prediction not possible
@@ -311,17 +465,40 @@ func (we *WEEnv) SetIsPredictable() {
 		return
 	}
 
+	if we.Words { // natural speech - the word labels mark the boundaries, within a word counts as fully predictable
+		switch we.CV.WordPos {
+		case WordInitial:
+			we.CV.Predictable = Partially
+		case WordMedial, WordFinal:
+			we.CV.Predictable = Fully
+		}
+		return
+	}
 	if we.SndTimit == true {
 		we.CV.Predictable = Partially
 		return
 	}
-	if we.CV.Ordinal%we.CVsPerWord == 0 {
+	pos := we.CV.Ordinal % we.CVsPerWord
+	if pos == 0 {
 		we.CV.Predictable = Partially
 		return
-	} else {
-		we.CV.Predictable = Fully
+	}
+	// within a word the CV is fully predictable only if it is the one CV that follows the previous CV
+	// in the training sequences -- without training bigrams, e.g. for timit, only if the two positions
+	// have the same number of CVs
+	if tp != nil && tp.NBigrams > 0 {
+		if tp.Fwd(we.CV.Last, we.CV.Cur) == 1 {
+			we.CV.Predictable = Fully
+		} else {
+			we.CV.Predictable = Partially
+		}
 		return
 	}
+	if pos < len(we.PosCVs) && len(we.PosCVs[pos]) > 1 && len(we.PosCVs[pos]) != len(we.PosCVs[pos-1]) {
+		we.CV.Predictable = Partially
+		return
+	}
+	we.CV.Predictable = Fully
 }
 
 // PredictableAsString const int returned as string
@@ -340,53 +517,79 @@ func (we *WEEnv) PartWholeAsString(p PartWhole) string {
 		return "Whole"
 	} else if p == PartWord {
 		return "Part"
+	} else if p == NonWord {
+		return "Non"
 	}
 	return ""
 }
 
 // SetIsPartWhole determines if the second CV is from the same word or different word (called part word in earlier literature)
-// These words are set for the run (experiment)
-func (we *WEEnv) SetIsPartWhole() {
+// or if the two CVs were never heard in this order (non word)
+// These words are set for the run (experiment) -- tp are the bigrams of the training sequences
+func (we *WEEnv) SetIsPartWhole(tp *TransProbs) {
 	we.CV.Word = NotPartNorWhole
 	if we.CV.Ordinal == 1 && we.CV.Last != we.CV.Cur { // i.e. only when we have just processed the first segment of the second CV
 		last := we.CV.Last
 		cur := we.CV.Cur
 
-		for i := 0; i < we.CVsPerPos; i++ {
-			if last == we.FirstCVs[i] && cur == we.SecondCVs[i] {
-				we.CV.Word = WholeWord
+		if len(we.PosCVs) < 2 {
+			return
+		}
+		if we.IsInWord(tp, 0, last, cur) {
+			we.CV.Word = WholeWord
+			return
+		}
+		if we.PosCVIdx(len(we.PosCVs)-1, last) >= 0 && we.PosCVIdx(0, cur) >= 0 {
+			we.CV.Word = PartWord
+			return
+		}
+		for pos := 1; pos < len(we.PosCVs)-1; pos++ {
+			if we.IsInWord(tp, pos, last, cur) { // heard in training but not at the start of a word
 				return
 			}
 		}
+		if we.IsPosCV(last) && we.IsPosCV(cur) { // both CVs are in the language but never in this order
+			we.CV.Word = NonWord
+			return
+		}
+	}
+}
 
-		if we.CVsPerWord == 2 {
-			for i := 0; i < we.CVsPerPos; i++ {
-				if last == we.SecondCVs[i] {
-					for j := 0; j < 4; j++ {
-						if cur == we.FirstCVs[j] {
-							we.CV.Word = PartWord
-							return
-						}
-					}
-				}
-			}
-		} else if we.CVsPerWord == 3 {
-			for i := 0; i < we.CVsPerPos; i++ {
-				if last == we.ThirdCVs[i] {
-					for j := 0; j < 4; j++ {
-						if cur == we.FirstCVs[j] {
-							we.CV.Word = PartWord
-							return
-						}
-					}
-				}
+// SetDependency determines if the current CV is in the dependent position of a nonadjacent frame,
+// i.e. the CV DepDist before starts a trained frame, and if so whether it completes a trained frame or violates it
+func (we *WEEnv) SetDependency() {
+	we.CV.DepDist = 0
+	we.CV.Dep = NoDependency
+	n := len(we.CV.Hist)
+	if we.DepDist < 2 || n <= we.DepDist || we.CV.Cur == "ss" {
+		return
+	}
+	first := we.CV.Hist[n-1-we.DepDist]
+	cur := we.CV.Hist[n-1]
+	isFirst := false
+	isEnd := false
+	for _, fr := range we.DepFrames {
+		if fr.Last == first {
+			if fr.Cur == cur {
+				we.CV.DepDist = we.DepDist
+				we.CV.Dep = TrainedFrame
+				return
 			}
+			isFirst = true
+		}
+		if fr.Cur == cur {
+			isEnd = true
 		}
 	}
+	if isFirst && isEnd {
+		we.CV.DepDist = we.DepDist
+		we.CV.Dep = ViolatedFrame
+	}
 }
 
 // ClearSoundsAndData empties the sound list, sets current sound to nothing, etc
 func (we *WEEnv) ClearSoundsAndData() {
+	we.StopPrefetch()
 	if we.SndFiles != nil {
 		we.SndFiles = we.SndFiles[:0]
 	}
@@ -395,10 +598,17 @@ func (we *WEEnv) ClearSoundsAndData() {
 	we.MaxSegCnt = 0
 	we.Trial.Max = 0
 	we.MoreSegments = false // this will force a new sound to be loaded
+	for _, se := range we.Snds {
+		se.Lead = nil // a continuous stream starts over
+	}
+	we.CV.Reset()
 }
 
 // LoadWavNames reads in a list of sound files names
 func (we *WEEnv) LoadWavNames() error {
+	if we.SndList == "" && we.IsRendered() {
+		return we.LoadSeqNames()
+	}
 	fp, err := os.Open(we.SndPath + we.SndList)
 	if err != nil {
 		log.Println(err)
@@ -407,6 +617,7 @@ func (we *WEEnv) LoadWavNames() error {
 	}
 	defer fp.Close() // we will be done with the file within this function
 
+	we.StopPrefetch()
 	scanner := bufio.NewScanner(fp)
 	scanner.Split(bufio.ScanLines)
 	// clear first in case it is called twice or the file changes
@@ -419,6 +630,24 @@ func (we *WEEnv) LoadWavNames() error {
 	return nil
 }
 
+// LoadSeqNames sets the sound files to all of the sequence files in SeqsPath, which is all that
+// is needed when the sequences are rendered rather than loaded from wav files
+func (we *WEEnv) LoadSeqNames() error {
+	files, err := ioutil.ReadDir(we.SndPath + we.SeqsPath)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	we.SndFiles = we.SndFiles[:0]
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		we.SndFiles = append(we.SndFiles, f.Name())
+	}
+	return nil
+}
+
 // LoadSoundsAndData loads
 func (we *WEEnv) LoadSoundsAndData() {
 	we.ClearSoundsAndData()
@@ -427,6 +656,7 @@ func (we *WEEnv) LoadSoundsAndData() {
 
 // SplitSndFiles pulls some of the training files out for testing - pass in the unique test file name
 func (we *WEEnv) SplitSndFiles(tstFileName string) {
+	we.StopPrefetch()
 	tstfiles := []string{}
 	trnfiles := []string{}
 	n := int(math.Trunc(float64(we.HoldoutPct) / 100 * float64(len(we.SndFiles))))
@@ -500,18 +730,75 @@ func (we *WEEnv) WriteTestList(list []string, filename string) {
 
 // LoadCVSeq reads in a list of cv strings for decoding a particular sequence
 func (we *WEEnv) LoadCVSeq(fn string) error {
+	seq, err := we.ReadCVSeq(fn)
+	if err != nil {
+		return err
+	}
+	we.SeqCur = seq
+	return nil
+}
+
+// ReadCVSeq reads the sequence file and returns the cv string
+func (we *WEEnv) ReadCVSeq(fn string) (seq string, err error) {
 	fp2, err2 := os.Open(we.SndPath + we.SeqsPath + fn)
 	if err2 != nil {
 		log.Println(err2)
-		return err2
+		return "", err2
 	}
 	defer fp2.Close() // we will be done with the file within this function
 	scanner2 := bufio.NewScanner(fp2)
 	scanner2.Split(bufio.ScanLines)
 	for scanner2.Scan() {
-		we.SeqCur = scanner2.Text()
+		seq = scanner2.Text()
 	}
-	return nil
+	return seq, nil
+}
+
+// SeqName returns the name of the sequence file for a sound file - the sound file name without
+// the ".wav" and anything after the second underscore
+func (we *WEEnv) SeqName(sndFile string) string {
+	fn := strings.TrimSuffix(sndFile, ".wav")
+	cnt := 0
+	for j := 0; j < len(fn); j++ {
+		if fn[j] == '_' {
+			cnt++
+		}
+		if cnt == 2 {
+			return fn[0:j]
+		}
+	}
+	return fn
+}
+
+// CalcTPs counts the CV bigrams of the sequences of all the sound files and from these
+// the transitional probabilities can be computed
+func (we *WEEnv) CalcTPs(tp *TransProbs) {
+	tp.Reset()
+	if we.SndTimit == true {
+		fmt.Println("CalcTPs: transitional probabilities are not computed for timit files")
+		return
+	}
+	for _, sf := range we.SndFiles {
+		fn := strings.TrimSuffix(sf, ".wav")
+		seq, err := we.ReadCVSeq(fn)
+		if err != nil {
+			continue
+		}
+		tp.AddSeq(we.SeqFields(seq))
+	}
+}
+
+// SetTPs sets the transitional probabilities and bigram frequency of the current CV transition
+func (we *WEEnv) SetTPs(tp *TransProbs) {
+	we.CV.FwdTP = 0
+	we.CV.BwdTP = 0
+	we.CV.BiFreq = 0
+	if we.CV.Ordinal < 1 || we.CV.Cur == "ss" || we.CV.Last == "" {
+		return
+	}
+	we.CV.FwdTP = tp.Fwd(we.CV.Last, we.CV.Cur)
+	we.CV.BwdTP = tp.Bwd(we.CV.Last, we.CV.Cur)
+	we.CV.BiFreq = tp.Freq(we.CV.Last, we.CV.Cur)
 }
 
 // SeqFields
@@ -520,7 +807,9 @@ func (we *WEEnv) SeqFields(seq string) []string {
 	return strings.Split(s, " ")
 }
 
-// NextSound will determine the next sound to load and load it - return error if end of sound list or actual error
+// NextSound will determine the next sound to load and load it - return error if end of sound list or actual error.
+// If Prefetch, the sound was drawn and loaded in the background during the last sound, and the sound after is drawn
+// and started loading.
 func (we *WEEnv) NextSound() (done bool, err error) {
 	done = false
 	if len(we.SndFiles) == 0 {
@@ -530,89 +819,153 @@ func (we *WEEnv) NextSound() (done bool, err error) {
 		return true, err
 	}
 
-	stop := we.NextSndFile() // will set we.SndIndx
-	if stop == true {
-		done = true
-		return done, nil
-	}
-	if we.SndIdx == -1 { // no sound or we exhausted the sounds - done
-		fmt.Println("SndIdx == -1")
-		err := error(nil)
-		return true, err
-	}
+	var lastCV *CVTime
+	if we.pf != nil {
+		done, err = we.AdoptPrefetch()
+		if done || err != nil {
+			return done, err
+		}
+	} else {
+		stop := we.NextSndFile() // will set we.SndIndx
+		if stop == true {
+			done = true
+			return done, nil
+		}
+		if we.SndIdx == -1 { // no sound or we exhausted the sounds - done
+			fmt.Println("SndIdx == -1")
+			err := error(nil)
+			return true, err
+		}
 
-	we.SndCur = we.SndFiles[we.SndIdx]
-	fp := we.SndPath + we.WavsPath + we.SndCur
+		if we.Continuous && we.SndCur != "" {
+			lastCV = we.CarrySound()
+		}
+		we.SndCur = we.SndFiles[we.SndIdx]
 
-	// add some random silence at start of sequence (up to 50ms)
-	we.msSilence = 0.0
-	if we.Silence {
-		we.msSilence = float64(rand.Intn(we.SilenceMax))
-	}
+		// add some random silence at start of sequence (up to 50ms)
+		we.msSilence = 0.0
+		if we.Continuous {
+			if se := we.Snds[0]; len(se.Lead) > 0 { // the carried samples take the place of the silence
+				we.msSilence = float64(SamplesToMSec(len(se.Lead[0])-se.Start, se.SampleRate()))
+			}
+		} else {
+			we.msSilence = we.DrawSilence()
+		}
+		if we.Augment.On {
+			we.Augment.Draw()
+		}
 
-	err = we.SndShort.Sound.Load(fp)
-	if err != nil {
-		log.Printf("NextSegment: error loading sound -- %v\n, err", we.SndCur)
-		return false, err
+		err = we.LoadSndFile()
+		if err != nil {
+			return false, err
+		}
 	}
 
-	// before loading sound, load the sequence times so we can drop the silence
-	// from the signal at start and end
-	fn := strings.TrimSuffix(we.SndCur, ".wav")
-	idx := 0
-	cnt := 0
-	for j := 0; j < len(fn); j++ {
-		if fn[j] == '_' {
-			cnt++
+	// the pathways look back and ahead different amounts so a continuous sound can fit different numbers
+	// of segments, only process the segments all of them have, the rest is carried to the next sound
+	we.MaxSegCnt = we.Snds[0].SegCnt
+	for _, se := range we.Snds[1:] {
+		if se.SegCnt < we.MaxSegCnt {
+			we.MaxSegCnt = se.SegCnt
 		}
-		if cnt == 2 {
-			idx = j
-			break
+	}
+	we.Trial.Max += we.MaxSegCnt
+	if we.Continuous {
+		we.JoinCVs(lastCV)
+		return done, err
+	}
+
+	// do some checks
+	for _, se := range we.Snds[1:] {
+		if se.SegCnt != we.Snds[0].SegCnt {
+			log.Printf("Segment count of %v: %d differs from %v: %d, using the smaller for we.Trial.Max!\n", se.Nm, se.SegCnt, we.Snds[0].Nm, we.Snds[0].SegCnt)
+			err = errors.New("Segment counts of the pathways differ, should only happen if the sounds are different")
 		}
 	}
+	we.CV.Reset()
+	we.StartPrefetch()
 
-	if idx == 0 {
-		we.SeqCur = fn
-	} else {
-		we.SeqCur = fn[0:idx]
+	return done, err
+}
+
+// DrawSilence returns the random milliseconds of silence to add at the start of the next sound, 0 if not Silence
+func (we *WEEnv) DrawSilence() float64 {
+	if !we.Silence {
+		return 0
 	}
-	we.TrialName = fn
-	if we.SndTimit == true {
-		we.LoadTimitSeqsAndTimes(fn)
-	} else {
-		we.LoadCVSeq(fn)
-		we.LoadCVTimes(fn)
+	ms := float64(we.Rnd().Intn(we.SilenceMax))
+	if we.IsCached() { // a limited number of silences, so a limited number of cached versions of the file
+		ms = we.FeatCache.Quantize(ms)
 	}
+	return ms
+}
 
-	we.SndShort.LoadSound()
-	we.InitSndShort()
-
-	err = we.SndLong.Sound.Load(fp)
-	if err != nil {
-		log.Printf("NextSegment: error loading sound -- %v\n, err", we.SndCur)
-		return false, err
-	}
-	we.SndLong.LoadSound()
-	we.InitSndLong()
-
-	// do some checks and set trial max
-	if we.SndLong.SegCnt < we.SndShort.SegCnt {
-		we.Trial.Max += we.SndLong.SegCnt
-		we.MaxSegCnt = we.SndLong.SegCnt
-		log.Println("Segment count long < short duration count, use this for we.Trial.Max!")
-		err = errors.New("Segment count long < short duration count, should only happen if the sounds are different")
-	} else if we.SndLong.SegCnt > we.SndShort.SegCnt {
-		we.Trial.Max += we.SndShort.SegCnt
-		we.MaxSegCnt = we.SndShort.SegCnt
-		log.Println("Segment count long > short duration count, use this for we.Trial.Max!")
-		err = errors.New("Segment count long > short duration count, should only happen if the sounds are different")
+// LoadSndFile loads or renders the sound SndCur, with msSilence already drawn, along with its sequence and times,
+// applies any prosody and augmentation and initializes the pathways
+func (we *WEEnv) LoadSndFile() error {
+	we.SetSndParams()
+	if we.IsRendered() { // no wav file - render the sequence into the signal
+		err := we.LoadRenderedSeq(we.SndCur)
+		if err != nil {
+			return err
+		}
 	} else {
-		we.Trial.Max += we.SndShort.SegCnt
-		we.MaxSegCnt = we.SndShort.SegCnt
+		fp := we.SndPath + we.WavsPath + we.SndCur
+		err := we.Snds[0].Sound.Load(fp)
+		if err != nil {
+			log.Printf("NextSegment: error loading sound -- %v\n, err", we.SndCur)
+			return err
+		}
+
+		// before loading sound, load the sequence times so we can drop the silence
+		// from the signal at start and end
+		fn := strings.TrimSuffix(we.SndCur, ".wav")
+		we.SeqCur = we.SeqName(we.SndCur)
+		we.TrialName = fn
+		if we.IsSeqNamed() {
+			we.LoadCVSeq(fn)
+		}
+		we.LoadTimes(fn)
+
+		we.Snds[0].LoadSound()
+		for _, se := range we.Snds[1:] {
+			se.ShareSound(we.Snds[0]) // the wav is only read once
+		}
 	}
-	we.CV.Reset()
+	if we.Prosody != nil {
+		we.ProsodySound()
+	}
+	if we.Augment.On {
+		we.AugmentSound()
+	}
+	we.InitSnds()
+	return nil
+}
 
-	return done, err
+// CarrySound carries the samples of the current sound not yet processed over to the start of the next
+// sound of a continuous stream, and returns the last CV of the current sound, which those samples are the end of,
+// or nil if there are no CVs
+func (we *WEEnv) CarrySound() *CVTime {
+	for _, se := range we.Snds {
+		se.Carry(we.MaxSegCnt)
+	}
+	if len(we.CVTimes) == 0 {
+		return nil
+	}
+	last := we.CVTimes[len(we.CVTimes)-1]
+	return &last
+}
+
+// JoinCVs joins the CVs of a newly loaded sound of a continuous stream to the CVs of the previous sound
+// by labelling the carried samples at the start of the signal as the last CV of the previous sound.
+// The CV state is not reset so the CV ordinals, history and transitions continue across the join.
+func (we *WEEnv) JoinCVs(lastCV *CVTime) {
+	if lastCV == nil || we.msSilence == 0 {
+		return
+	}
+	// only the alpha times are used by CVLookup
+	cvt := CVTime{Name: lastCV.Name, EndAlpha: we.AdjustCVTime(we.msSilence/1000, false)}
+	we.CVTimes = append([]CVTime{cvt}, we.CVTimes...)
 }
 
 func (we *WEEnv) NextSndFile() (stop bool) {
@@ -626,12 +979,12 @@ func (we *WEEnv) NextSndFile() (stop bool) {
 	if we.SeqOrder == RandomOrder {
 		if we.RepeatOk {
 			n := sfc / nproc
-			we.SndIdx = rand.Intn(n) + mpi.WorldRank()*n
+			we.SndIdx = we.Rnd().Intn(n) + mpi.WorldRank()*n
 			//log.Printf("SndIdx: %v, rank: %v, file: %v\n", we.SndIdx, mpi.WorldRank(), we.SndFiles[we.SndIdx])
 		} else {
 			for {
 				n := sfc / nproc
-				we.SndIdx = rand.Intn(n) + mpi.WorldRank()*n
+				we.SndIdx = we.Rnd().Intn(n) + mpi.WorldRank()*n
 				sndNext := strings.TrimSuffix(we.SndFiles[we.SndIdx], ".wav")
 				sndNext = strings.TrimPrefix(sndNext, "")
 				if len(we.SndCur) > 0 {
@@ -661,138 +1014,300 @@ func (we *WEEnv) NextSndFile() (stop bool) {
 
 // NextSegment calls to process the next segment of sound, loading a new sound if the last sound was fully processed
 func (we *WEEnv) NextSegment() error {
-	//fmt.Println("seg / max seg", we.SndShort.Segment, we.MaxSegCnt)
-	if we.MoreSegments == false || we.SndShort.Segment == we.MaxSegCnt {
-		done, err := we.NextSound()
-		if done && err == nil {
-			return err
+	//fmt.Println("seg / max seg", we.CurSeg(), we.MaxSegCnt)
+	if we.MoreSegments == false || we.CurSeg() == we.MaxSegCnt {
+		for {
+			done, err := we.NextSound()
+			if done && err == nil {
+				return err
+			}
+			if err != nil {
+				return err
+			}
+			if !we.Continuous || we.MaxSegCnt > 0 { // a sound too short for a segment is all carried to the next
+				break
+			}
 		}
-		if err != nil {
-			return err
+	}
+	more := we.Snds[0].ProcessSegment()
+	sync := true
+	for _, se := range we.Snds[1:] {
+		if se.ProcessSegment() != more {
+			sync = false
 		}
 	}
-	moreShort := we.SndShort.ProcessSegment()
-	moreLong := we.SndLong.ProcessSegment()
 
-	if moreShort != moreLong {
+	if we.Continuous {
+		we.MoreSegments = we.CurSeg()+1 < we.MaxSegCnt
+		return nil
+	}
+	if !sync {
 		return errors.New("Sequence lengths out of sync - could there be a bug in the padding of the signal?")
 	}
-	we.MoreSegments = moreShort
+	we.MoreSegments = more
 	return nil
 }
 
-// LoadCVTimes loads the timing and sequence (transcription) data for CV files
-func (we *WEEnv) LoadCVTimes(fn string) error {
-	we.CVTimes = nil
+// IsCached returns true if the segments of the current sound are cached -- augmented, rendered and continuous
+// sounds differ each time they are loaded so are not cached
+func (we *WEEnv) IsCached() bool {
+	return we.FeatCache.On && !we.Augment.On && !we.Continuous && !we.IsRendered()
+}
+
+// OpenCache opens the cache of the current sound for the pathway, after it is initialized -- st and end
+// are the times the sound was trimmed to, passed to Init, and are part of the key along with any prosody
+func (we *WEEnv) OpenCache(se *SndEnv, st, end float64) {
+	if !we.IsCached() {
+		se.Cache.Close()
+		return
+	}
+	extra := fmt.Sprintf("%v %v", st, end)
+	if we.Prosody != nil {
+		extra += fmt.Sprintf(" %+v", *we.Prosody)
+	}
+	se.Cache.Open(we.FeatCache.FileName(we.SndPath+we.WavsPath+we.SndCur, se, we.msSilence, extra), se)
+}
 
-	// load the CV start/end times produced by Audacity "sound finder"
-	fp, err := os.Open(we.SndPath + we.TimesPath + fn + ".txt")
+// LabelReader returns the reader for the label files in TimesPath
+func (we *WEEnv) LabelReader() LabelReader {
+	return NewLabelReader(we.TimesFormat, we.TimesTier, we.SndTimit)
+}
+
+// LoadTimes loads the start and end times of the CVs (or phones, or words) of the sound from its label file.
+// Audacity labels only have times so the CV names come from the current sequence, and for the other formats
+// the names are the labels, leaving out silence, and the current sequence is set from them.
+func (we *WEEnv) LoadTimes(fn string) error {
+	we.CVTimes = nil
+	lr := we.LabelReader()
+	if we.SndTimit {
+		fn = strings.Replace(fn, "_Wavs", "", 1) // wav files stored in a different directory so fix the filename
+	}
+	labels, err := lr.Read(we.SndPath + we.TimesPath + fn + lr.Ext())
 	if err != nil {
 		log.Println(err)
 		log.Println("Make sure you have the sound files rsyncd to your ccn_images directory and a link (ln -s) to ccn_images in your sim working directory")
 		return err
 	}
-	defer fp.Close() // we will be done with the file within this function
-
-	scanner := bufio.NewScanner(fp)
-	scanner.Split(bufio.ScanLines)
 
-	silence := we.msSilence / 1000.0
-	offset := 0.0 // first start time might not be zero - could be a section of longer sequence
-	flds := we.SeqFields(we.SeqCur)
-	i := 0
-	for scanner.Scan() {
-		t := scanner.Text()
-		if t == "" {
-			break
-		} else if strings.HasPrefix(t, "\\") { // lines starting with '/' are lines with frequency for start/end points
-			continue
+	if we.IsSeqNamed() {
+		flds := we.SeqFields(we.SeqCur)
+		for i, l := range labels {
+			if i == len(flds) { // handles case where there may be lines after last line of start, end, name
+				break
+			}
+			we.CVTimes = append(we.CVTimes, CVTime{Name: flds[i], Start: l.Start, End: l.End})
+		}
+	} else {
+		var names []string
+		for _, l := range labels {
+			if lr.IsSilence(l.Name) {
+				continue
+			}
+			we.CVTimes = append(we.CVTimes, CVTime{Name: l.Name, Start: l.Start, End: l.End})
+			names = append(names, l.Name)
 		}
-		cvt := new(CVTime)
-		we.CVTimes = append(we.CVTimes, *cvt)
-		cvs := strings.Fields(t)
-		f, err := strconv.ParseFloat(cvs[0], 64)
-		if len(we.CVTimes) == 1 { // first start/end time - save offset
-			offset = f
-		}
-		if err == nil {
-			we.CVTimes[i].Start = f
-			f += silence - offset
-			we.CVTimes[i].StartAlpha = we.AdjustCVTime(f, true)
-		}
-		f, err = strconv.ParseFloat(cvs[1], 64)
-		if err == nil {
-			we.CVTimes[i].End = f
-			f += silence - offset
-			we.CVTimes[i].EndAlpha = we.AdjustCVTime(f, false)
-		}
-		we.CVTimes[i].Name = flds[i]
-		i++
-		if i == len(flds) {
-			return nil
-		} // handles case where there may be lines after last line of start, end, name
+		we.SeqCur = strings.Join(names, " ")
+	}
+	if we.Words {
+		we.LoadWords(fn)
 	}
+	we.SetAlphaTimes()
 	return nil
 }
 
-// LoadTimitSeqsTimes loads the timing and sequence (transcription) data for timit files
-func (we *WEEnv) LoadTimitSeqsAndTimes(fn string) error {
-	we.CVTimes = nil // the sounds aren't CVs but the idea is the same
+// WordsReader returns the reader for the word label files
+func (we *WEEnv) WordsReader() LabelReader {
+	format := we.WordsFormat
+	if format == DefaultLabels {
+		format = we.TimesFormat
+		if we.SndTimit {
+			format = TimitWrd
+		}
+	}
+	return NewLabelReader(format, we.WordsTier, we.SndTimit)
+}
 
-	// load the sound start/end times shipped with the TIMIT database
-	// wav files stored in a different directory so fix the filename
-	fn = strings.Replace(fn, "_Wavs", "", 1)
-	fp, err := os.Open(we.SndPath + we.TimesPath + fn + ".PHN.MS") // PHN is "Phone" and MS is milliseconds
+// LoadWords loads the word labels of the sound and sets the position within its word of each of the CVTimes.
+// A CV is in the word its midpoint falls within, and CVs not within any word, e.g. pauses, have NoWordPos.
+// fn is the file name already fixed by LoadTimes
+func (we *WEEnv) LoadWords(fn string) error {
+	lr := we.WordsReader()
+	words, err := lr.Read(we.SndPath + we.TimesPath + fn + lr.Ext())
 	if err != nil {
 		log.Println(err)
-		log.Println("Make sure you have the sound files rsyncd to ccn_images directory and a link (ln -s) to ccn_images in your sim working directory")
 		return err
 	}
-	defer fp.Close() // we will be done with the file within this function
-
-	scanner := bufio.NewScanner(fp)
-	scanner.Split(bufio.ScanLines)
-
-	silence := we.msSilence / 1000.0
-	i := 0
-	for scanner.Scan() {
-		t := scanner.Text()
-		if t == "" {
-			break
+	wi := 0
+	first := -1 // index of the first CV of the current word
+	for i := range we.CVTimes {
+		cvt := &we.CVTimes[i]
+		cvt.WordPos = NoWordPos
+		mid := (cvt.Start + cvt.End) / 2
+		for wi < len(words) && mid >= words[wi].End {
+			wi++
+			first = -1
 		}
-		if strings.Contains(t, "h#") { // silence at start or silence at end
-			if len(we.CVTimes) == 0 { // starting silence
-				continue
-			} else { // silence at end
-				cvs := strings.Fields(t)
-				f, _ := strconv.ParseFloat(cvs[0], 64)
-				f = f / 1000 // convert from ms to seconds
-				we.CVTimes[i-1].End = f
-				f += silence
-				we.CVTimes[i-1].EndAlpha = we.AdjustCVTime(f, true)
-				break // we're done!
-			}
+		if wi == len(words) || mid < words[wi].Start || lr.IsSilence(words[wi].Name) {
+			continue
 		}
-		cvt := new(CVTime)
-		we.CVTimes = append(we.CVTimes, *cvt)
-		cvs := strings.Fields(t)
-		f, err := strconv.ParseFloat(cvs[0], 64)
-		f = f / 1000 // convert from ms to seconds
-		if err == nil {
-			we.CVTimes[i].Start = f
-			f += silence
-			we.CVTimes[i].StartAlpha = we.AdjustCVTime(f, true)
+		if first < 0 {
+			first = i
+			cvt.WordPos = WordInitial
+			continue
 		}
-		if len(we.CVTimes) > 1 {
-			we.CVTimes[i-1].End = we.CVTimes[i].Start
-			we.CVTimes[i-1].EndAlpha = we.CVTimes[i].StartAlpha
+		cvt.WordPos = WordFinal
+		if i-1 > first {
+			we.CVTimes[i-1].WordPos = WordMedial
 		}
-		we.CVTimes[i].Name = cvs[1] //
-		i++
 	}
 	return nil
 }
 
+// IsSeqNamed returns true if the names of the CVs come from the sequence files rather than the label files
+func (we *WEEnv) IsSeqNamed() bool {
+	_, ok := we.LabelReader().(*AudacityReader)
+	return ok
+}
+
+// IsRendered returns true if the sequences are rendered directly into the signal, by the tone
+// language or the synthesizer, rather than loaded from wav files
+func (we *WEEnv) IsRendered() bool {
+	return we.ToneLang != nil || we.Synth != nil
+}
+
+// LoadRenderedSeq loads the sequence of tone or syllable names and renders it into the signal of the pathways,
+// setting the CVTimes from the rendered times rather than from a label file
+func (we *WEEnv) LoadRenderedSeq(fn string) error {
+	fn = strings.TrimSuffix(fn, ".wav")
+	we.TrialName = fn
+	err := we.LoadCVSeq(fn)
+	if err != nil {
+		return err
+	}
+	flds := we.SeqFields(we.SeqCur)
+	if we.Synth != nil {
+		err = we.RenderSynth(flds)
+	} else {
+		for i, se := range we.Snds {
+			times, rerr := we.ToneLang.Render(flds, &se.Signal)
+			se.Sound = we.ToneLang.Wave()
+			if i == 0 {
+				we.CVTimes, err = times, rerr
+			}
+		}
+	}
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	for _, se := range we.Snds {
+		se.ResampleSignal()
+	}
+
+	silence := we.msSilence / 1000.0
+	for i := range we.CVTimes {
+		we.CVTimes[i].StartAlpha = we.AdjustCVTime(we.CVTimes[i].Start+silence, true)
+		we.CVTimes[i].EndAlpha = we.AdjustCVTime(we.CVTimes[i].End+silence, false)
+	}
+	return nil
+}
+
+// RenderSynth renders the syllables with the synthesizer into the signal of the pathways and
+// sets the start and end times of the CVTimes from the synthesizer labels
+func (we *WEEnv) RenderSynth(cvs []string) error {
+	vals, labels, err := we.Synth.Render(cvs)
+	if err != nil {
+		return err
+	}
+	for _, se := range we.Snds {
+		se.Sound.Buf = &audio.IntBuffer{Format: &audio.Format{NumChannels: 1, SampleRate: we.Synth.SampleRate}, SourceBitDepth: 16}
+		se.Signal.SetShape([]int{len(vals)}, nil, nil)
+		copy(se.Signal.Values, vals)
+	}
+	we.CVTimes = make([]CVTime, len(labels))
+	for i, l := range labels {
+		we.CVTimes[i] = CVTime{Name: l.Name, Start: l.Start, End: l.End}
+	}
+	return nil
+}
+
+// AugmentSound applies the current draw of the augmentation to the signal of each pathway, at the sample rate of
+// the pathway, and rescales the CV times to match any time stretch. Each channel of a multichannel signal
+// is augmented separately. The draw is made before the sound is loaded, on the main goroutine, so a
+// sound loaded in the background does not use the random source.
+func (we *WEEnv) AugmentSound() {
+	for _, se := range we.Snds {
+		sr := se.SampleRate()
+		ch := 0
+		se.MapChannels(func(sig []float32) []float32 {
+			aug := we.Augment.Apply(sig, sr, ch)
+			ch++
+			return aug
+		})
+	}
+	we.RescaleCVTimes(we.Augment.CurStr)
+}
+
+// ProsodySound adds the prosodic cues to each channel of the signal of each pathway, at the sample rate of
+// the pathway, and sets the CV times to the times of the cued syllables
+func (we *WEEnv) ProsodySound() {
+	if len(we.CVTimes) == 0 {
+		return
+	}
+	var times []CVTime
+	for i, se := range we.Snds {
+		sr := se.SampleRate()
+		se.MapChannels(func(sig []float32) []float32 {
+			out, nt := we.Prosody.Apply(sig, sr, we.CVTimes)
+			if i == 0 {
+				times = nt
+			}
+			return out
+		})
+	}
+	we.CVTimes = times
+	we.SetAlphaTimes()
+}
+
+// CueCond returns how the prosodic cues of the sounds relate to the word boundaries, NoCue if there is no prosody
+func (we *WEEnv) CueCond() CueCond {
+	if we.Prosody == nil {
+		return NoCue
+	}
+	return we.Prosody.Cond()
+}
+
+// RescaleCVTimes multiplies the CV start and end times by f, for a signal that has been stretched in time by f,
+// and recomputes the alpha aligned times
+func (we *WEEnv) RescaleCVTimes(f float64) {
+	if f == 1 || len(we.CVTimes) == 0 {
+		return
+	}
+	for i := range we.CVTimes {
+		cvt := &we.CVTimes[i]
+		cvt.Start *= f
+		cvt.End *= f
+	}
+	we.SetAlphaTimes()
+}
+
+// SetAlphaTimes computes the alpha aligned times of the CVs from their start and end times
+func (we *WEEnv) SetAlphaTimes() {
+	if len(we.CVTimes) == 0 {
+		return
+	}
+	silence := we.msSilence / 1000.0
+	offset := 0.0 // the alpha times of CV sequences are relative to the first start time
+	if !we.SndTimit {
+		offset = we.CVTimes[0].Start
+	}
+	for i := range we.CVTimes {
+		cvt := &we.CVTimes[i]
+		cvt.StartAlpha = we.AdjustCVTime(cvt.Start+silence-offset, true)
+		cvt.EndAlpha = we.AdjustCVTime(cvt.End+silence-offset, false)
+	}
+}
+
 // AdjustCVTimes adds some leeway around the absolute times.
 // We need this because we only collect stats every 100ms and with the absolute times
 // you can miss whole CVs if under 100ms (rare) but also we don't want to miss the first
@@ -823,7 +1338,8 @@ func (we *WEEnv) AdjustCVTime(v float64, start bool) float64 {
 // sequence is the full sound sequence loaded from file, a subseq is a sequence of segments of a particular CV, e.g. papapa
 func (we *WEEnv) CVLookup() {
 	cv := ""
-	stride := float64(we.SndShort.Params.StrideMs)
+	wpos := NoWordPos
+	stride := float64(we.Snds[0].Params.StrideMs)
 	time := float64(we.CurSeg())*stride + stride // add one stride to get to end of the segment
 	last := len(we.CVTimes) - 1
 	for _, cvt := range we.CVTimes {
@@ -835,6 +1351,7 @@ func (we *WEEnv) CVLookup() {
 			continue
 		} else if time >= cvt.StartAlpha && time <= cvt.EndAlpha {
 			cv = cvt.Name
+			wpos = cvt.WordPos
 			break
 		} else {
 			cv = "ss" // silence
@@ -846,11 +1363,16 @@ func (we *WEEnv) CVLookup() {
 		we.CV.Last = we.CV.Cur
 	}
 	we.CV.Cur = cv
+	we.CV.WordPos = wpos
 	if we.CV.Last == we.CV.Cur {
 		we.CV.SubSeg++
 	} else {
 		if we.CV.Last != "ss" && we.CV.Cur != "ss" { // only update if next CV, silence doesn't count!
 			we.CV.Ordinal++
+			we.CV.Hist = append(we.CV.Hist, we.CV.Cur)
+			if n := len(we.CV.Hist) - (we.DepDist + 1); n > 0 { // only SetDependency looks back, DepDist CVs
+				we.CV.Hist = append(we.CV.Hist[:0], we.CV.Hist[n:]...)
+			}
 		}
 		we.CV.SubSeg = 0 // 0 for new CV or if silent segment part
 	}
@@ -912,6 +1434,21 @@ func (we *WEEnv) Init(run int) {
 	we.CV.Predicted["CPBTh_CV"] = ""
 	we.CV.Predicted["RPBTh_CV"] = ""
 	we.CV.Predicted["STSTh_CV"] = ""
+	we.StopPrefetch() // drawn with the random state of the last run
+	we.Augment.Init(run)
+}
+
+// InitRand seeds the random source of the sound order and silence draws
+func (we *WEEnv) InitRand(seed int64) {
+	we.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Rnd returns the random source of the sound order and silence draws, seeded from the global source if not yet seeded
+func (we *WEEnv) Rnd() *rand.Rand {
+	if we.Rand == nil {
+		we.InitRand(rand.Int63())
+	}
+	return we.Rand
 }
 
 func (we *WEEnv) Step() bool {
@@ -937,23 +1474,40 @@ func (we *WEEnv) Step() bool {
 	return true
 }
 
+// State returns the gabor output of the pathway whose layer is the element, e.g. "A1" or "R", for the first
+// channel of the sound, or the channel after an underscore, e.g. "A1_1" is the second ear
 func (we *WEEnv) State(element string) (et etensor.Tensor) {
-	if element == "A1" {
-		if we.SndShort.Kwta.On == true {
-			et = &we.SndShort.GborKwta
-		} else {
-			et = &we.SndShort.GborOutput
-		}
-	} else if element == "R" {
-		if we.SndLong.Kwta.On == true {
-			et = &we.SndLong.GborKwta
-		} else {
-			et = &we.SndLong.GborOutput
+	ch := 0
+	if i := strings.LastIndex(element, "_"); i >= 0 {
+		if c, err := strconv.Atoi(element[i+1:]); err == nil {
+			ch = c
+			element = element[:i]
 		}
-	} else {
+	}
+	if pi := we.Paths.ByFeatLayer(element); pi >= 0 {
+		we.CheckChannel(pi, ch)
+		return we.Snds[pi].FeatInput(ch)
+	}
+	pi, err := we.Paths.ByLayerTry(element)
+	if err != nil {
 		log.Println("State: element not known - check spelling, especially case!")
+		return nil
+	}
+	we.CheckChannel(pi, ch)
+	if we.Paths[pi].FeatLayer == "" {
+		return we.Snds[pi].InputWithFeats(ch)
+	}
+	return we.Snds[pi].Output(ch)
+}
+
+// CheckChannel warns, once for the env, if channel ch of pathway pi is past the channels of the sound,
+// e.g. the second ear of a mono sound, which gets the last channel, the same as the first ear
+func (we *WEEnv) CheckChannel(pi, ch int) {
+	if we.chWarned || ch < we.Snds[pi].Channels() {
+		return
 	}
-	return et
+	log.Printf("%v: sound %v has %d channel(s), the input of channel %d is channel %d -- use a stimulus set with Channel -1 and stereo sounds for binaural input\n", we.Nm, we.SndCur, we.Snds[pi].Channels(), ch, we.Snds[pi].Channels()-1)
+	we.chWarned = true
 }
 
 func (we *WEEnv) Action(element string, input etensor.Tensor) {
@@ -977,5 +1531,5 @@ func (we *WEEnv) Counter(scale env.TimeScales) (cur, prv int, chg bool) {
 }
 
 func (we *WEEnv) CurSeg() int {
-	return we.SndShort.Segment
+	return we.Snds[0].Segment
 }
@@ -28,6 +28,9 @@ type WordNet struct {
 	SuperLays    []string      `interactive:"-" desc:"superficial layer names"`
 	HidLays      []string      `interactive:"-" desc:"superficial layer names"`
 	TRCLays      []string      `interactive:"+" desc:"TRC layer names"`
+	Binaural     bool          `desc:"separate input layers for the two ears (channels) of stereo sounds - A1 and R get the first channel, A1_1 and R_1 the second, and both ears project to and drive the same belt and STS layers"`
+	InLays       []InLay       `desc:"input layers of the pathways, in the order of the pathways, see SetInputs"`
+	FeatLays     []InLay       `desc:"input layers of the feature columns of the pathways that apply them to a layer of their own"`
 
 	// Projections
 	Topo22Skp11Prjn      *prjn.PoolTile `view:"-" desc:"feedforward topo prjn 2x by 2y, skip 1x, skip 1y"`
@@ -55,6 +58,26 @@ type WordNet struct {
 	Topo32Skp01PrjnRecip *prjn.PoolTile `view:"-" desc:"topo reciprocal projection"`
 }
 
+// InLay is an input layer of a pathway, or of the feature columns of a pathway -- one layer per ear if Binaural
+type InLay struct {
+	Name   string `desc:"name of the layer"`
+	Belt   string `desc:"the belt layer the input projects to, CB or RB"`
+	PoolsY int    `desc:"number of pools along the frequency axis"`
+	PoolsX int    `desc:"number of pools along the time axis"`
+	Cols   int    `desc:"number of columns of each pool -- the gabor filters and any feature columns added after them"`
+}
+
+// Belts are the belt layers of the caudal and rostral streams, which the input layers of the pathways project to
+var Belts = []string{"CB", "RB"}
+
+// BeltClass returns the class of the input layers of the belt -- A1 for the caudal belt and R for the rostral
+func BeltClass(belt string) string {
+	if belt == "RB" {
+		return "R"
+	}
+	return "A1"
+}
+
 // New creates new blank elements and initializes defaults
 func NewWordNet() *WordNet {
 	wn := WordNet{}
@@ -139,24 +162,123 @@ func (wn *WordNet) NewPrjns() {
 	wn.Topo32Skp01PrjnRecip.TopoRange.Min = 0.8
 }
 
+// InLay returns the input layer of the pathway of which the layer nm is an ear, nil if none
+func (wn *WordNet) InLay(nm string) *InLay {
+	for i := range wn.InLays {
+		for _, en := range wn.EarLays(wn.InLays[i].Name) {
+			if en == nm {
+				return &wn.InLays[i]
+			}
+		}
+	}
+	return nil
+}
+
+// EarLays returns the names of the input layers of the pathway, e.g. A1, one per ear if Binaural
+func (wn *WordNet) EarLays(nm string) []string {
+	if wn.Binaural {
+		return []string{nm, nm + "_1"}
+	}
+	return []string{nm}
+}
+
+// SetInputs sets the input layers and the feature input layers from the pathways, before Config --
+// each pathway has an input layer of its own, of PoolsY by PoolsX pools of the gabor filters plus any
+// feature columns. It returns an error for a pathway that has no layer or belt, or whose layer is
+// used by another pathway, and if a belt is not driven by any pathway.
+func (wn *WordNet) SetInputs(paths Pathways) error {
+	wn.InLays = nil
+	wn.FeatLays = nil
+	used := make(map[string]string)
+	for _, pw := range paths {
+		if pw.Layer == "" {
+			return fmt.Errorf("WordNet: pathway %v has no input layer", pw.Name)
+		}
+		if pw.Belt != "CB" && pw.Belt != "RB" {
+			return fmt.Errorf("WordNet: pathway %v has belt %q, which must be CB or RB", pw.Name, pw.Belt)
+		}
+		if pw.PoolsY <= 0 || pw.PoolsX <= 0 {
+			return fmt.Errorf("WordNet: pathway %v has %d by %d pools", pw.Name, pw.PoolsY, pw.PoolsX)
+		}
+		nc := pw.FeatCols()
+		lays := []string{pw.Layer}
+		if nc > 0 && pw.FeatLayer != "" {
+			lays = append(lays, pw.FeatLayer)
+		}
+		for _, nm := range lays {
+			if on, ok := used[nm]; ok {
+				return fmt.Errorf("WordNet: pathways %v and %v both use layer %v", on, pw.Name, nm)
+			}
+			used[nm] = pw.Name
+		}
+		il := InLay{Name: pw.Layer, Belt: pw.Belt, PoolsY: pw.PoolsY, PoolsX: pw.PoolsX, Cols: pw.Gabor.Params().NFilters}
+		if nc > 0 {
+			if pw.FeatLayer == "" {
+				il.Cols += nc
+			} else {
+				wn.FeatLays = append(wn.FeatLays, InLay{Name: pw.FeatLayer, Belt: pw.Belt, PoolsY: pw.PoolsY, PoolsX: pw.PoolsX, Cols: nc})
+			}
+		}
+		wn.InLays = append(wn.InLays, il)
+	}
+	for _, bt := range Belts {
+		if len(wn.BeltIns(bt)) == 0 {
+			return fmt.Errorf("WordNet: no pathway drives belt %v", bt)
+		}
+	}
+	return nil
+}
+
+// BeltIns returns the names of the input layers of the belt, all of the belts if belt is empty --
+// one per ear if Binaural, in the order the layers are made, the ears of every pathway in turn
+func (wn *WordNet) BeltIns(belt string) []string {
+	var nms []string
+	nears := len(wn.EarLays(""))
+	for ear := 0; ear < nears; ear++ {
+		for _, il := range wn.InLays {
+			if belt == "" || il.Belt == belt {
+				nms = append(nms, wn.EarLays(il.Name)[ear])
+			}
+		}
+	}
+	return nms
+}
+
+// BeltCols returns the most columns of the pools of the input layers of the belt, all of the belts if
+// belt is empty -- the drivers of a pulvinar layer are packed in order, so the widest of them sets its columns
+func (wn *WordNet) BeltCols(belt string) int {
+	nx := 0
+	for _, il := range wn.InLays {
+		if (belt == "" || il.Belt == belt) && il.Cols > nx {
+			nx = il.Cols
+		}
+	}
+	return nx
+}
+
 func (wn *WordNet) Config() {
 	net := wn.Net
 	net.InitName(net, "WordSeg")
 
-	// primary auditory
-	a1s := net.AddLayer4D("A1", 12, 6, 2, 7, emer.Input)
-	a1s.SetClass("A1")
-
-	rs := net.AddLayer4D("R", 12, 6, 2, 7, emer.Input)
-	rs.SetClass("R")
+	// primary auditory -- an input layer for each pathway, one per ear if Binaural, in the caudal or rostral
+	// stream of its belt -- the pulvinar layers are driven by every input of their stream, so their pools hold all of them
+	ins := make(map[string]emer.Layer)
+	for _, nm := range wn.BeltIns("") {
+		il := wn.InLay(nm)
+		ly := net.AddLayer4D(nm, il.PoolsY, il.PoolsX, 2, il.Cols, emer.Input)
+		ly.SetClass(BeltClass(il.Belt))
+		ins[nm] = ly
+	}
+	cins := wn.BeltIns("CB")
+	rins := wn.BeltIns("RB")
 
 	one2one := prjn.NewOneToOne()
 	pOne2One := prjn.NewPoolOneToOne()
 
 	// belt (B)
 	cbs, cbct, cbth := net.AddDeep4D("CB", 5, 4, 5, 5)
-	cbth.Shape().SetShape([]int{5, 4, 2, 7}, nil, nil)
-	cbth.(*deep.TRCLayer).Drivers.Add("A1")
+	cbth.Shape().SetShape([]int{5, 4, 2 * len(cins), wn.BeltCols("CB")}, nil, nil)
+	cbth.(*deep.TRCLayer).Drivers.Add(cins...)
 	cbs.SetClass("CB")
 	cbct.SetClass("CB")
 	cbth.SetClass("CB")
@@ -165,8 +287,8 @@ func (wn *WordNet) Config() {
 	cbth.SetName("CBTh")
 
 	rbs, rbct, rbth := net.AddDeep4D("RB", 5, 4, 5, 5)
-	rbth.Shape().SetShape([]int{5, 4, 2, 7}, nil, nil)
-	rbth.(*deep.TRCLayer).Drivers.Add("R")
+	rbth.Shape().SetShape([]int{5, 4, 2 * len(rins), wn.BeltCols("RB")}, nil, nil)
+	rbth.(*deep.TRCLayer).Drivers.Add(rins...)
 	rbs.SetClass("RB")
 	rbct.SetClass("RB")
 	rbth.SetClass("RB")
@@ -176,8 +298,8 @@ func (wn *WordNet) Config() {
 
 	// parabelt (PB)
 	cpbs, cpbct, cpbth := net.AddDeep4D("CPB", 5, 3, 5, 5)
-	cpbth.Shape().SetShape([]int{5, 3, 2, 7}, nil, nil)
-	cpbth.(*deep.TRCLayer).Drivers.Add("A1")
+	cpbth.Shape().SetShape([]int{5, 3, 2 * len(cins), wn.BeltCols("CB")}, nil, nil)
+	cpbth.(*deep.TRCLayer).Drivers.Add(cins...)
 	cpbs.SetClass("CPB")
 	cpbct.SetClass("CPBCT")
 	cpbth.SetClass("CPBTH")
@@ -186,8 +308,8 @@ func (wn *WordNet) Config() {
 	cpbth.SetName("CPBTh")
 
 	rpbs, rpbct, rpbth := net.AddDeep4D("RPB", 5, 3, 5, 5)
-	rpbth.Shape().SetShape([]int{5, 3, 2, 7}, nil, nil)
-	rpbth.(*deep.TRCLayer).Drivers.Add("R")
+	rpbth.Shape().SetShape([]int{5, 3, 2 * len(rins), wn.BeltCols("RB")}, nil, nil)
+	rpbth.(*deep.TRCLayer).Drivers.Add(rins...)
 	rpbs.SetClass("RPB")
 	rpbct.SetClass("RPBCT")
 	rpbth.SetClass("RPBTH")
@@ -197,8 +319,8 @@ func (wn *WordNet) Config() {
 
 	// superior temporal
 	stss, stsct, ststh := net.AddDeep4D("STS", 5, 3, 6, 6)
-	ststh.Shape().SetShape([]int{5, 3, 4, 7}, nil, nil)
-	ststh.(*deep.TRCLayer).Drivers.Add("A1", "R")
+	ststh.Shape().SetShape([]int{5, 3, 2 * (len(cins) + len(rins)), wn.BeltCols("")}, nil, nil)
+	ststh.(*deep.TRCLayer).Drivers.Add(append(append([]string{}, cins...), rins...)...)
 	stss.SetClass("STS")
 	stsct.SetClass("STSCT")
 	ststh.SetClass("STSTH")
@@ -206,18 +328,25 @@ func (wn *WordNet) Config() {
 	stsct.RecvPrjns().SendName("STS").SetClass("ToCT1to1")
 	ststh.SetName("STSTh")
 
-	a1s.SetRelPos(relpos.Rel{Scale: 1.0})
-	rs.SetRelPos(relpos.Rel{Rel: relpos.LeftOf, Other: "A1", XAlign: relpos.Left, Space: 10, Scale: 1.0})
+	// the caudal inputs run to the right of the first and the rostral inputs to the left of it
+	ins[cins[0]].SetRelPos(relpos.Rel{Scale: 1.0})
+	for i, nm := range cins[1:] {
+		ins[nm].SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: cins[i], YAlign: relpos.Front, Space: 10, Scale: 1.0})
+	}
+	ins[rins[0]].SetRelPos(relpos.Rel{Rel: relpos.LeftOf, Other: cins[0], XAlign: relpos.Left, Space: 10, Scale: 1.0})
+	for i, nm := range rins[1:] {
+		ins[nm].SetRelPos(relpos.Rel{Rel: relpos.LeftOf, Other: rins[i], YAlign: relpos.Front, Space: 10, Scale: 1.0})
+	}
 
-	cbs.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "A1", XAlign: relpos.Left, Space: 50})
+	cbs.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: cins[0], XAlign: relpos.Left, Space: 50})
 	cbct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "CB", XAlign: relpos.Left, Space: 20})
 	cbth.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "CBCT", XAlign: relpos.Left, Space: 20, Scale: 1})
 
-	rbs.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "R", XAlign: relpos.Left, Space: 50})
+	rbs.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: rins[0], XAlign: relpos.Left, Space: 50})
 	rbct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "RB", XAlign: relpos.Left, Space: 20})
 	rbth.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "RBCT", XAlign: relpos.Left, Space: 20, Scale: 1})
 
-	cpbs.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: "A1", XAlign: relpos.Left, YAlign: relpos.Front})
+	cpbs.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: cins[0], XAlign: relpos.Left, YAlign: relpos.Front})
 	cpbct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "CPB", XAlign: relpos.Left, Space: 20, Scale: 1.0})
 	cpbth.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "CPBCT", XAlign: relpos.Left, Space: 20, Scale: 1.0})
 
@@ -229,10 +358,31 @@ func (wn *WordNet) Config() {
 	stsct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "STS", XAlign: relpos.Left, Space: 20, Scale: 1.0})
 	ststh.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: "STSCT", XAlign: relpos.Left, Space: 20, Scale: 1.0})
 
-	// primary to belt
-	net.ConnectLayers(a1s, cbs, wn.Topo33Skp12Prjn, emer.Forward).SetClass("FwdStd")
-	net.ConnectLayers(rs, rbs, wn.Topo33Skp12Prjn, emer.Forward).SetClass("FwdStd")
-	net.ConnectLayers(a1s, rbs, wn.Topo33Skp12Prjn, emer.Forward).SetClass("A1ToRB")
+	// primary to belt -- the caudal inputs also feed the rostral belt
+	belts := map[string]emer.Layer{"CB": cbs, "RB": rbs}
+	for _, ear := range wn.EarLays("") {
+		for _, il := range wn.InLays {
+			nm := il.Name + ear
+			net.ConnectLayers(ins[nm], belts[il.Belt], wn.Topo33Skp12Prjn, emer.Forward).SetClass("FwdStd")
+		}
+		for _, il := range wn.InLays {
+			if il.Belt == "CB" {
+				net.ConnectLayers(ins[il.Name+ear], rbs, wn.Topo33Skp12Prjn, emer.Forward).SetClass("A1ToRB")
+			}
+		}
+	}
+
+	// feature layers to the belt of their pathway
+	prv := cins[len(cins)-1]
+	for _, fl := range wn.FeatLays {
+		for _, nm := range wn.EarLays(fl.Name) {
+			fls := net.AddLayer4D(nm, fl.PoolsY, fl.PoolsX, 2, fl.Cols, emer.Input)
+			fls.SetClass("Feat")
+			fls.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: prv, YAlign: relpos.Front, Space: 10, Scale: 1.0})
+			prv = nm
+			net.ConnectLayers(fls, belts[fl.Belt], wn.Topo33Skp12Prjn, emer.Forward).SetClass("FwdStd")
+		}
+	}
 
 	// superficial belt to parabelt
 	net.ConnectLayers(cbs, cpbs, wn.Topo22Skp11Prjn, emer.Forward).SetClass("FwdStd")
@@ -317,7 +467,12 @@ func (wn *WordNet) Config() {
 	//ar := net.ThreadReport() // hand tuning now..
 	//mpi.Printf("%s", ar)
 
-	a1s.SetThread(0)
+	for _, nm := range cins {
+		ins[nm].SetThread(0)
+	}
+	for _, nm := range rins {
+		ins[nm].SetThread(1)
+	}
 
 	cbs.SetThread(0)
 	cbct.SetThread(0)
@@ -327,8 +482,6 @@ func (wn *WordNet) Config() {
 	cpbct.SetThread(0)
 	cpbth.SetThread(0)
 
-	rs.SetThread(1)
-
 	rbs.SetThread(1)
 	rbct.SetThread(1)
 	rbth.SetThread(1)
@@ -363,7 +516,7 @@ func (wn *WordNet) ParamsName() string {
 func (wn *WordNet) SetParams(sheet string, setMsg bool) error {
 	if sheet == "" {
 		// this is important for catching typos and ensuring that all sheets can be used
-		wn.Params.ValidateSheets([]string{"Network", "Sim"})
+		wn.Params.ValidateSheets([]string{"Network", "Sim", "TrainEnv", "TestEnv"})
 	}
 	err := wn.SetParamsSet("Base", sheet, setMsg)
 	if wn.ParamSet != "" && wn.ParamSet != "Base" {
@@ -397,7 +550,26 @@ func (wn *WordNet) SetParamsSet(setNm string, sheet string, setMsg bool) error {
 			simp.Apply(wn, setMsg)
 		}
 	}
-	// note: if you have more complex environments with parameters, definitely add
-	// sheets for them, e.g., "TrainEnv", "TestEnv" etc
+	// the "TrainEnv" and "TestEnv" sheets are applied by the envs, see EnvSheets
 	return err
 }
+
+// EnvSheets returns the env sheet of the given name, e.g., "TrainEnv", for "Base" and then
+// the current ParamSet, in the order they are applied -- see WEEnv.SetParams
+func (wn *WordNet) EnvSheets(sheet string) []*params.Sheet {
+	setNms := []string{"Base"}
+	if wn.ParamSet != "" && wn.ParamSet != "Base" {
+		setNms = append(setNms, wn.ParamSet)
+	}
+	var shs []*params.Sheet
+	for _, setNm := range setNms {
+		pset, err := wn.Params.SetByNameTry(setNm)
+		if err != nil {
+			continue // reported by SetParams
+		}
+		if sh, ok := pset.Sheets[sheet]; ok {
+			shs = append(shs, sh)
+		}
+	}
+	return shs
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package synth
+
+// PhoneKind is the manner of articulation of a phone, which determines how it is rendered
+type PhoneKind int32
+
+const (
+	// Vowel is voiced with steady formants
+	Vowel PhoneKind = iota
+
+	// Stop is a closure followed by a burst and, if voiceless, aspiration
+	Stop
+
+	// Fricative is frication noise, plus voicing if voiced
+	Fricative
+
+	// Nasal is voiced with a low first formant and reduced amplitude
+	Nasal
+
+	// Approximant is voiced with formants that glide into the next phone (l, r, w, y)
+	Approximant
+
+	// Aspirate is aspiration noise shaped by the formants of the following vowel (h)
+	Aspirate
+)
+
+// Phone is one phone of the inventory - for consonants F holds the formant loci
+// that the formant transitions into and out of the neighboring vowels start from
+type Phone struct {
+	Name   string     `desc:"the letter used for the phone in syllable names"`
+	Kind   PhoneKind  `desc:"manner of articulation"`
+	Voiced bool       `desc:"is the phone voiced"`
+	F      [3]float64 `desc:"first three formant frequencies, or the loci for consonants"`
+	FricHz float64    `desc:"center frequency of the frication noise of fricatives or of the burst of stops"`
+	FricBw float64    `desc:"bandwidth of the frication or burst noise"`
+	Amp    float64    `desc:"relative amplitude of the voicing of vowels, nasals and approximants, or of the noise of the other kinds"`
+}
+
+// DefPhones returns the default inventory - one letter per phone, values are approximately
+// those of an adult male speaker (Klatt, 1980; Peterson & Barney, 1952)
+func DefPhones() map[string]*Phone {
+	phs := []*Phone{
+		{Name: "a", Kind: Vowel, Voiced: true, F: [3]float64{730, 1090, 2440}, Amp: 1},
+		{Name: "e", Kind: Vowel, Voiced: true, F: [3]float64{530, 1840, 2480}, Amp: 1},
+		{Name: "i", Kind: Vowel, Voiced: true, F: [3]float64{310, 2290, 3010}, Amp: 1},
+		{Name: "o", Kind: Vowel, Voiced: true, F: [3]float64{570, 840, 2410}, Amp: 1},
+		{Name: "u", Kind: Vowel, Voiced: true, F: [3]float64{320, 870, 2240}, Amp: 1},
+
+		{Name: "p", Kind: Stop, F: [3]float64{250, 800, 2100}, FricHz: 1000, FricBw: 2000, Amp: 0.5},
+		{Name: "b", Kind: Stop, Voiced: true, F: [3]float64{250, 800, 2100}, FricHz: 1000, FricBw: 2000, Amp: 0.3},
+		{Name: "t", Kind: Stop, F: [3]float64{250, 1700, 2600}, FricHz: 4000, FricBw: 1500, Amp: 0.7},
+		{Name: "d", Kind: Stop, Voiced: true, F: [3]float64{250, 1700, 2600}, FricHz: 4000, FricBw: 1500, Amp: 0.5},
+		{Name: "k", Kind: Stop, F: [3]float64{250, 1800, 2200}, FricHz: 2000, FricBw: 800, Amp: 0.7},
+		{Name: "g", Kind: Stop, Voiced: true, F: [3]float64{250, 1800, 2200}, FricHz: 2000, FricBw: 800, Amp: 0.5},
+
+		{Name: "f", Kind: Fricative, F: [3]float64{300, 900, 2100}, FricHz: 5000, FricBw: 4000, Amp: 0.3},
+		{Name: "v", Kind: Fricative, Voiced: true, F: [3]float64{300, 900, 2100}, FricHz: 5000, FricBw: 4000, Amp: 0.2},
+		{Name: "s", Kind: Fricative, F: [3]float64{300, 1700, 2600}, FricHz: 5500, FricBw: 1000, Amp: 1},
+		{Name: "z", Kind: Fricative, Voiced: true, F: [3]float64{300, 1700, 2600}, FricHz: 5500, FricBw: 1000, Amp: 0.6},
+
+		{Name: "m", Kind: Nasal, Voiced: true, F: [3]float64{250, 1000, 2200}, Amp: 0.5},
+		{Name: "n", Kind: Nasal, Voiced: true, F: [3]float64{250, 1700, 2600}, Amp: 0.5},
+
+		{Name: "l", Kind: Approximant, Voiced: true, F: [3]float64{360, 1300, 2900}, Amp: 0.7},
+		{Name: "r", Kind: Approximant, Voiced: true, F: [3]float64{330, 1060, 1380}, Amp: 0.7},
+		{Name: "w", Kind: Approximant, Voiced: true, F: [3]float64{290, 610, 2150}, Amp: 0.7},
+		{Name: "y", Kind: Approximant, Voiced: true, F: [3]float64{260, 2070, 3020}, Amp: 0.7},
+
+		{Name: "h", Kind: Aspirate, Amp: 0.5},
+	}
+	inv := make(map[string]*Phone, len(phs))
+	for _, ph := range phs {
+		inv[ph.Name] = ph
+	}
+	return inv
+}
@@ -0,0 +1,373 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package synth is a small Klatt style formant synthesizer (Klatt, 1980) that renders sequences of
+// CV syllables directly to samples, along with the exact start and end time of each syllable,
+// so that a language can be simulated from its sequences alone, without a corpus of wav files.
+//
+// A voicing source (an impulse train at the fundamental, smoothed by a low pass glottal filter)
+// and an aspiration noise source feed a cascade of four formant resonators. Frication and stop
+// bursts are noise through a parallel resonator. All parameters move linearly from the targets
+// of one phone to those of the next, which gives the formant transitions that carry the place
+// of articulation of the consonants.
+package synth
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/go-audio/audio"
+)
+
+// Synth holds the parameters for rendering syllables
+type Synth struct {
+	SampleRate int               `def:"16000" desc:"sample rate of the rendered signal"`
+	F0         float64           `def:"120" desc:"fundamental frequency in hertz at the start of a sequence"`
+	F0End      float64           `def:"100" desc:"fundamental frequency at the end of a sequence - pitch declines linearly from F0 - 0 for a flat pitch"`
+	SylMs      float64           `def:"0" desc:"if > 0 every syllable is this many milliseconds, the vowel being lengthened or shortened to fit, otherwise syllable duration depends on its phones"`
+	VowelMs    float64           `def:"200" desc:"duration of vowels, when SylMs is 0"`
+	ConsMs     float64           `def:"70" desc:"duration of nasals, approximants and h"`
+	FricMs     float64           `def:"100" desc:"duration of fricatives"`
+	ClosureMs  float64           `def:"50" desc:"duration of the closure of stops"`
+	BurstMs    float64           `def:"10" desc:"duration of the release burst of stops"`
+	VOTMs      float64           `def:"40" desc:"duration of the aspiration after the burst of voiceless stops (voice onset time)"`
+	TransMs    float64           `def:"40" desc:"duration of the formant transitions between consonants and vowels"`
+	GapMs      float64           `def:"0" desc:"milliseconds of silence between syllables, 0 for a continuous stream"`
+	F4         float64           `def:"3300" desc:"frequency of the fourth formant, which is the same for all phones"`
+	Bw         [4]float64        `desc:"bandwidths of the four cascade formants"`
+	Amp        float64           `def:"0.5" desc:"peak amplitude of a rendered sequence, 0 to 1"`
+	Seed       int64             `def:"1" desc:"seed for the noise sources, so that a sequence always renders the same"`
+	Phones     map[string]*Phone `view:"-" json:"-" desc:"the phone inventory - DefPhones if nil"`
+}
+
+// Defaults sets the default parameters
+func (sy *Synth) Defaults() {
+	sy.SampleRate = 16000
+	sy.F0 = 120
+	sy.F0End = 100
+	sy.SylMs = 0
+	sy.VowelMs = 200
+	sy.ConsMs = 70
+	sy.FricMs = 100
+	sy.ClosureMs = 50
+	sy.BurstMs = 10
+	sy.VOTMs = 40
+	sy.TransMs = 40
+	sy.GapMs = 0
+	sy.F4 = 3300
+	sy.Bw = [4]float64{80, 100, 150, 250}
+	sy.Amp = 0.5
+	sy.Seed = 1
+}
+
+// UnmarshalJSON sets the defaults before decoding so that a json definition
+// only needs the parameters that differ from the defaults
+func (sy *Synth) UnmarshalJSON(b []byte) error {
+	type params Synth // no methods, so no recursion
+	sy.Defaults()
+	return json.Unmarshal(b, (*params)(sy))
+}
+
+// Label is the name and the start and end time, in seconds, of one rendered syllable
+type Label struct {
+	Name  string
+	Start float64
+	End   float64
+}
+
+// gains of the noise sources relative to voicing, so that the Amp of the phones
+// gives about the loudness of natural speech, e.g. a vowel well above an s
+const (
+	aspGain  = 0.07
+	fricGain = 0.03
+)
+
+// frame is the set of parameters that are interpolated from phone to phone
+type frame struct {
+	F      [4]float64
+	AV     float64 // voicing amplitude
+	AH     float64 // aspiration amplitude
+	AF     float64 // frication amplitude
+	FricHz float64
+	FricBw float64
+}
+
+// segment is a stretch of samples over which the parameters move to the target
+// frame over trans samples and then hold
+type segment struct {
+	n     int
+	trans int
+	tgt   frame
+}
+
+// SylPhones returns the phones of the syllable, one per letter, and an error if any letter is not in the inventory
+func (sy *Synth) SylPhones(syl string) ([]*Phone, error) {
+	if sy.Phones == nil {
+		sy.Phones = DefPhones()
+	}
+	var phs []*Phone
+	for _, r := range syl {
+		ph, ok := sy.Phones[string(r)]
+		if !ok {
+			return nil, errors.New("synth.SylPhones: no phone for letter " + string(r) + " of syllable " + syl)
+		}
+		phs = append(phs, ph)
+	}
+	return phs, nil
+}
+
+// samples returns the number of samples for the given milliseconds
+func (sy *Synth) samples(ms float64) int {
+	return int(math.Round(ms * float64(sy.SampleRate) / 1000))
+}
+
+// formants returns the four formants for the first three given
+func (sy *Synth) formants(f [3]float64) [4]float64 {
+	return [4]float64{f[0], f[1], f[2], sy.F4}
+}
+
+// sylSegments returns the segments of one syllable
+func (sy *Synth) sylSegments(phs []*Phone) []segment {
+	// formants of the next vowel, for aspiration which takes the shape of the vowel it precedes
+	nextV := func(i int) [4]float64 {
+		for j := i + 1; j < len(phs); j++ {
+			if phs[j].Kind == Vowel {
+				return sy.formants(phs[j].F)
+			}
+		}
+		return sy.formants(phs[i].F)
+	}
+	voicebar := func(ph *Phone) float64 {
+		if ph.Voiced {
+			return 0.1
+		}
+		return 0
+	}
+
+	var segs []segment
+	var vowels []int // indexes of the vowel segments, for fitting to SylMs
+	cons := 0        // samples of everything but vowels
+	for i, ph := range phs {
+		switch ph.Kind {
+		case Vowel:
+			vowels = append(vowels, len(segs))
+			segs = append(segs, segment{n: sy.samples(sy.VowelMs), trans: sy.samples(sy.TransMs), tgt: frame{F: sy.formants(ph.F), AV: ph.Amp}})
+			continue
+		case Stop:
+			f := sy.formants(ph.F)
+			segs = append(segs, segment{n: sy.samples(sy.ClosureMs), trans: sy.samples(10), tgt: frame{F: f, AV: voicebar(ph)}})
+			segs = append(segs, segment{n: sy.samples(sy.BurstMs), trans: 1, tgt: frame{F: f, AV: voicebar(ph), AF: ph.Amp, FricHz: ph.FricHz, FricBw: ph.FricBw}})
+			if !ph.Voiced {
+				segs = append(segs, segment{n: sy.samples(sy.VOTMs), trans: sy.samples(sy.TransMs), tgt: frame{F: nextV(i), AH: ph.Amp}})
+			}
+		case Fricative:
+			segs = append(segs, segment{n: sy.samples(sy.FricMs), trans: sy.samples(20), tgt: frame{F: sy.formants(ph.F), AV: 3 * voicebar(ph), AF: ph.Amp, FricHz: ph.FricHz, FricBw: ph.FricBw}})
+		case Nasal:
+			segs = append(segs, segment{n: sy.samples(sy.ConsMs), trans: sy.samples(20), tgt: frame{F: sy.formants(ph.F), AV: ph.Amp}})
+		case Approximant:
+			segs = append(segs, segment{n: sy.samples(sy.ConsMs), trans: sy.samples(sy.TransMs), tgt: frame{F: sy.formants(ph.F), AV: ph.Amp}})
+		case Aspirate:
+			segs = append(segs, segment{n: sy.samples(sy.ConsMs), trans: sy.samples(10), tgt: frame{F: nextV(i), AH: ph.Amp}})
+		}
+	}
+	for _, s := range segs {
+		cons += s.n
+	}
+	if sy.SylMs > 0 && len(vowels) > 0 {
+		for _, v := range vowels {
+			cons -= segs[v].n
+		}
+		vn := (sy.samples(sy.SylMs) - cons) / len(vowels)
+		if vn < sy.samples(40) {
+			vn = sy.samples(40)
+		}
+		for _, v := range vowels {
+			segs[v].n = vn
+		}
+	}
+	return segs
+}
+
+// Render renders the sequence of syllables and returns the samples, in the range -1 to 1,
+// and the label of each syllable. Silence ("ss") renders as silence the length of a syllable
+// and gets a label like the other syllables.
+func (sy *Synth) Render(syls []string) ([]float32, []Label, error) {
+	if sy.SampleRate <= 0 {
+		return nil, nil, errors.New("synth.Render: SampleRate must be greater than zero")
+	}
+	sr := float64(sy.SampleRate)
+	var segs []segment
+	var labels []Label
+	pos := 0
+	for _, syl := range syls {
+		if syl == "" {
+			continue
+		}
+		if len(segs) > 0 && sy.GapMs > 0 {
+			gap := segment{n: sy.samples(sy.GapMs), trans: sy.samples(10), tgt: segs[len(segs)-1].tgt}
+			gap.tgt.AV, gap.tgt.AH, gap.tgt.AF = 0, 0, 0
+			segs = append(segs, gap)
+			pos += gap.n
+		}
+		var ss []segment
+		if syl == "ss" {
+			n := sy.samples(sy.SylMs)
+			if n == 0 {
+				n = sy.samples(sy.VowelMs + sy.ConsMs)
+			}
+			ss = append(ss, segment{n: n, trans: sy.samples(10)})
+			if len(segs) > 0 {
+				ss[0].tgt.F = segs[len(segs)-1].tgt.F
+			}
+		} else {
+			phs, err := sy.SylPhones(syl)
+			if err != nil {
+				return nil, nil, err
+			}
+			ss = sy.sylSegments(phs)
+		}
+		st := pos
+		for _, s := range ss {
+			pos += s.n
+		}
+		segs = append(segs, ss...)
+		labels = append(labels, Label{Name: syl, Start: float64(st) / sr, End: float64(pos) / sr})
+	}
+	if len(segs) == 0 {
+		return nil, labels, nil
+	}
+	// fade out at the end rather than stopping mid period
+	end := segment{n: sy.samples(20), trans: sy.samples(20), tgt: segs[len(segs)-1].tgt}
+	end.tgt.AV, end.tgt.AH, end.tgt.AF = 0, 0, 0
+	segs = append(segs, end)
+	pos += end.n
+
+	vals := make([]float32, pos)
+	sy.synthesize(segs, vals)
+	return vals, labels, nil
+}
+
+// synthesize renders the segments into vals, which must be the total length of the segments
+func (sy *Synth) synthesize(segs []segment, vals []float32) {
+	sr := float64(sy.SampleRate)
+	rnd := rand.New(rand.NewSource(sy.Seed))
+	var glot, fric resonator
+	glot.set(0, 100, sr)
+	var casc [4]resonator
+
+	cur := segs[0].tgt // start from silence at the formants of the first segment
+	cur.AV, cur.AH, cur.AF = 0, 0, 0
+	f0end := sy.F0End
+	if f0end <= 0 {
+		f0end = sy.F0
+	}
+	phase := 1.0 // start with a glottal pulse
+	lastOut := 0.0
+	peak := 0.0
+	idx := 0
+	for _, s := range segs {
+		from := cur
+		for i := 0; i < s.n; i++ {
+			p := 1.0
+			if i < s.trans {
+				p = float64(i+1) / float64(s.trans)
+			}
+			cur = lerp(from, s.tgt, p)
+
+			f0 := sy.F0 + (f0end-sy.F0)*float64(idx)/float64(len(vals))
+			pulse := 0.0
+			phase += f0 / sr
+			if phase >= 1 {
+				phase -= 1
+				pulse = sr / f0 // so the glottal flow averages 1 over a period
+			}
+			noise := 2*rnd.Float64() - 1
+
+			src := cur.AV*glot.step(pulse) + aspGain*cur.AH*noise
+			for j := range casc {
+				casc[j].set(cur.F[j], sy.Bw[j], sr)
+				src = casc[j].step(src)
+			}
+			if cur.AF > 0 {
+				fric.set(cur.FricHz, cur.FricBw, sr)
+			}
+			out := src + fricGain*cur.AF*fric.bandpass(noise)
+			v := out - lastOut // radiation from the lips is a first difference
+			lastOut = out
+			vals[idx] = float32(v)
+			if math.Abs(v) > peak {
+				peak = math.Abs(v)
+			}
+			idx++
+		}
+	}
+	if peak > 0 {
+		g := float32(sy.Amp / peak)
+		for i := range vals {
+			vals[i] *= g
+		}
+	}
+}
+
+// lerp returns the frame p of the way from a to b
+func lerp(a, b frame, p float64) frame {
+	var f frame
+	for i := range f.F {
+		f.F[i] = a.F[i] + p*(b.F[i]-a.F[i])
+	}
+	f.AV = a.AV + p*(b.AV-a.AV)
+	f.AH = a.AH + p*(b.AH-a.AH)
+	f.AF = a.AF + p*(b.AF-a.AF)
+	f.FricHz = b.FricHz
+	f.FricBw = b.FricBw
+	if b.AF == 0 { // keep the noise shape while frication fades out
+		f.FricHz = a.FricHz
+		f.FricBw = a.FricBw
+	}
+	return f
+}
+
+// resonator is a second order digital resonator (Klatt, 1980 eq. 2)
+type resonator struct {
+	a, b, c float64
+	y1, y2  float64
+}
+
+// set sets the coefficients for the given center frequency and bandwidth in hertz
+func (r *resonator) set(f, bw, sr float64) {
+	r.c = -math.Exp(-2 * math.Pi * bw / sr)
+	r.b = 2 * math.Exp(-math.Pi*bw/sr) * math.Cos(2*math.Pi*f/sr)
+	r.a = 1 - r.b - r.c
+}
+
+// step filters one sample, with unity gain at zero frequency
+func (r *resonator) step(x float64) float64 {
+	y := r.a*x + r.b*r.y1 + r.c*r.y2
+	r.y2 = r.y1
+	r.y1 = y
+	return y
+}
+
+// bandpass filters one sample, with approximately unity gain at the center frequency,
+// which is the appropriate scaling for the parallel (frication) branch
+func (r *resonator) bandpass(x float64) float64 {
+	g := 1 + r.c // (1 - r^2) normalizes the peak gain of the two pole resonator
+	y := g*x + r.b*r.y1 + r.c*r.y2
+	r.y2 = r.y1
+	r.y1 = y
+	return y
+}
+
+// IntBuffer converts rendered samples to a 16 bit, one channel buffer, e.g. for writing a wav file
+// with sound.Wave.WriteWave
+func (sy *Synth) IntBuffer(vals []float32) *audio.IntBuffer {
+	buf := &audio.IntBuffer{Format: &audio.Format{NumChannels: 1, SampleRate: sy.SampleRate}, SourceBitDepth: 16}
+	buf.Data = make([]int, len(vals))
+	for i, v := range vals {
+		buf.Data[i] = int(math.Round(float64(v) * 0x7FFF))
+	}
+	return buf
+}
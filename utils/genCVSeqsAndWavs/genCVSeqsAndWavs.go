@@ -457,6 +457,7 @@ func Chunks(s string, chunkSize int) []string {
 }
 
 // GenSpeech calls gnuspeech on the content of each file in dir
+// -- the trm_param_file.txt parameter file is read from the working directory, i.e., run this from utils/genCVSeqsAndWavs
 func (gn *Gen) GenSpeech(dirIn, dirOut string) {
 	files := gn.LoadFileNamesFromDir(dirIn, "")
 
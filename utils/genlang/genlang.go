@@ -0,0 +1,447 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/emer/etable/etview" // include to get gui views
+	"github.com/goki/gi/gi"
+	"github.com/goki/gi/gimain"
+	"github.com/goki/gi/giv"
+	"github.com/goki/ki/ki"
+)
+
+// genlang generates sequences of CVs for languages with an arbitrary transitional probability structure,
+// either from a word list with per-word frequencies or from a CV to CV transition matrix.
+// For each sequence a sequence file (CVs separated by spaces, as read by the wordseg sim) and a label file
+// with the expected start/end times of each CV (Audacity label format, in seconds) are written.
+// The realized transitional probabilities are reported so the stream can be checked against the design.
+
+// this is the stub main for gogi that calls our actual
+// mainrun function, at end of file
+func main() {
+	gimain.Main(func() {
+		mainrun()
+	})
+}
+
+// Word is a word of the language and its relative frequency
+type Word struct {
+	CVs  []string
+	Freq float64
+}
+
+// CVPair is a pair of adjacent CVs, Last followed by Cur
+type CVPair struct {
+	Last string
+	Cur  string
+}
+
+type Gen struct {
+	Seqs       [][]string      `view:"no-inline" desc:"the generated sequences of CVs"`
+	Words      []Word          `view:"no-inline" desc:"the words and their frequencies read from WordFile"`
+	CVs        []string        `view:"no-inline" desc:"the CVs of the transition matrix, in row / column order"`
+	Matrix     [][]float64     `view:"no-inline" desc:"the transition matrix read from MatrixFile - row is the current CV, column the next CV, rows are normalized to sum to 1"`
+	WordFile   string          `desc:"file of words, one per line, CVs separated by spaces, optionally followed by a tab and the relative frequency of the word (default 1)"`
+	MatrixFile string          `desc:"file with the CVs on the first line and then one line per CV: the CV followed by the transition probabilities (or counts) to each of the CVs"`
+	SeqsDir    string          `desc:"directory of where to write the sequence files"`
+	TimesDir   string          `desc:"directory of where to write the label files with the expected CV timing"`
+	ReportFile string          `desc:"file to write the realized transitional probabilities to - if empty only printed"`
+	Prefix     string          `desc:"fixed part of file name before id"`
+	StructView *giv.StructView `view:"-" desc:"the params viewer"`
+	NSeqs      int             `desc:"the number of sequences to create"`
+	NWords     int             `desc:"the number of words per sequence, when generating from words"`
+	NCVs       int             `desc:"the number of CVs per sequence, when generating from the matrix"`
+	NoRepeats  bool            `desc:"never follow a word with the same word, when generating from words"`
+	CVMs       float64         `desc:"expected duration of each CV in milliseconds"`
+	GapMs      float64         `desc:"expected silence between CVs in milliseconds"`
+	RndSeed    int64           `desc:"the random seed - same seed gives the same sequences"`
+}
+
+func NewGen() *Gen {
+	g := Gen{}
+	g.NSeqs = 24
+	g.NWords = 12
+	g.NCVs = 36
+	g.NoRepeats = true
+	g.CVMs = 250
+	g.GapMs = 0
+	g.RndSeed = 1
+	g.Prefix = "001"
+	return &g
+}
+
+// Reset clears the sequences, words and matrix
+func (gn *Gen) Reset() {
+	gn.Seqs = nil
+	gn.Words = nil
+	gn.CVs = nil
+	gn.Matrix = nil
+}
+
+// LoadWords loads the words and their frequencies from WordFile
+func (gn *Gen) LoadWords() error {
+	fp, err := os.Open(gn.WordFile)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer fp.Close() // we will be done with the file within this function
+
+	gn.Words = nil
+	scanner := bufio.NewScanner(fp)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		flds := strings.Split(scanner.Text(), "\t")
+		cvs := strings.Fields(flds[0])
+		if len(cvs) == 0 {
+			continue
+		}
+		w := Word{CVs: cvs, Freq: 1}
+		if len(flds) > 1 {
+			f, err := strconv.ParseFloat(strings.TrimSpace(flds[1]), 64)
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			w.Freq = f
+		}
+		gn.Words = append(gn.Words, w)
+	}
+	return nil
+}
+
+// LoadMatrix loads the CVs and the transition matrix from MatrixFile
+func (gn *Gen) LoadMatrix() error {
+	fp, err := os.Open(gn.MatrixFile)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer fp.Close() // we will be done with the file within this function
+
+	gn.CVs = nil
+	gn.Matrix = nil
+	scanner := bufio.NewScanner(fp)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		flds := strings.Fields(scanner.Text())
+		if len(flds) == 0 {
+			continue
+		}
+		if gn.CVs == nil {
+			gn.CVs = flds
+			continue
+		}
+		if len(flds) != len(gn.CVs)+1 {
+			err = fmt.Errorf("LoadMatrix: row for %v has %d values, expected %d", flds[0], len(flds)-1, len(gn.CVs))
+			log.Println(err)
+			return err
+		}
+		row := make([]float64, len(gn.CVs))
+		sum := 0.0
+		for i, f := range flds[1:] {
+			row[i], err = strconv.ParseFloat(f, 64)
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			sum += row[i]
+		}
+		if sum > 0 {
+			for i := range row {
+				row[i] /= sum
+			}
+		}
+		gn.Matrix = append(gn.Matrix, row)
+	}
+	if len(gn.Matrix) != len(gn.CVs) {
+		err = fmt.Errorf("LoadMatrix: %d rows for %d CVs", len(gn.Matrix), len(gn.CVs))
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// Choose returns an index chosen at random in proportion to the weights, -1 if all weights are zero
+func Choose(wts []float64) int {
+	sum := 0.0
+	for _, w := range wts {
+		sum += w
+	}
+	if sum <= 0 {
+		return -1
+	}
+	r := rand.Float64() * sum
+	for i, w := range wts {
+		r -= w
+		if r < 0 {
+			return i
+		}
+	}
+	return len(wts) - 1
+}
+
+// GenFromWords generates NSeqs sequences of NWords words, with words chosen in proportion to their frequency
+func (gn *Gen) GenFromWords() {
+	if len(gn.Words) == 0 {
+		fmt.Println("gn.Words is empty, load words first")
+		return
+	}
+	rand.Seed(gn.RndSeed)
+	gn.Seqs = nil
+	wts := make([]float64, len(gn.Words))
+	for i := 0; i < gn.NSeqs; i++ {
+		seq := []string{}
+		prv := -1
+		for j := 0; j < gn.NWords; j++ {
+			for k, w := range gn.Words {
+				wts[k] = w.Freq
+			}
+			if gn.NoRepeats && prv >= 0 && len(gn.Words) > 1 {
+				wts[prv] = 0
+			}
+			idx := Choose(wts)
+			if idx < 0 {
+				fmt.Println("GenFromWords: all word frequencies are zero")
+				return
+			}
+			seq = append(seq, gn.Words[idx].CVs...)
+			prv = idx
+		}
+		gn.Seqs = append(gn.Seqs, seq)
+	}
+	gn.Report()
+}
+
+// GenFromMatrix generates NSeqs sequences of NCVs CVs by walking the transition matrix.
+// The first CV of each sequence is chosen at random.
+func (gn *Gen) GenFromMatrix() {
+	if len(gn.Matrix) == 0 {
+		fmt.Println("gn.Matrix is empty, load matrix first")
+		return
+	}
+	rand.Seed(gn.RndSeed)
+	gn.Seqs = nil
+	for i := 0; i < gn.NSeqs; i++ {
+		seq := []string{}
+		cur := rand.Intn(len(gn.CVs))
+		for j := 0; j < gn.NCVs; j++ {
+			seq = append(seq, gn.CVs[cur])
+			nxt := Choose(gn.Matrix[cur])
+			if nxt < 0 {
+				break // no way out of this CV
+			}
+			cur = nxt
+		}
+		gn.Seqs = append(gn.Seqs, seq)
+	}
+	gn.Report()
+}
+
+// Report prints the realized forward and backward transitional probabilities and the frequency
+// of each bigram in the generated sequences, along with the target forward probability if generated from the matrix
+func (gn *Gen) Report() {
+	bis := make(map[CVPair]int)
+	lasts := make(map[string]int)
+	curs := make(map[string]int)
+	n := 0
+	for _, seq := range gn.Seqs {
+		for i := 1; i < len(seq); i++ {
+			bis[CVPair{seq[i-1], seq[i]}]++
+			lasts[seq[i-1]]++
+			curs[seq[i]]++
+			n++
+		}
+	}
+	prs := make([]CVPair, 0, len(bis))
+	for pr := range bis {
+		prs = append(prs, pr)
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		if prs[i].Last == prs[j].Last {
+			return prs[i].Cur < prs[j].Cur
+		}
+		return prs[i].Last < prs[j].Last
+	})
+
+	var sb strings.Builder
+	sb.WriteString("Last\tCur\tCount\tFreq\tFwdTP\tBwdTP\tTargetTP\n")
+	for _, pr := range prs {
+		c := float64(bis[pr])
+		tgt := ""
+		if len(gn.Matrix) > 0 {
+			tgt = strconv.FormatFloat(gn.TargetTP(pr), 'f', 4, 64)
+		}
+		sb.WriteString(fmt.Sprintf("%v\t%v\t%d\t%.4f\t%.4f\t%.4f\t%v\n", pr.Last, pr.Cur, bis[pr], c/float64(n), c/float64(lasts[pr.Last]), c/float64(curs[pr.Cur]), tgt))
+	}
+	fmt.Print(sb.String())
+	if gn.ReportFile != "" {
+		f, err := os.Create(gn.ReportFile)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		defer f.Close()
+		f.WriteString(sb.String())
+	}
+}
+
+// TargetTP returns the forward transitional probability of the pair in the transition matrix, -1 if not in matrix
+func (gn *Gen) TargetTP(pr CVPair) float64 {
+	li := -1
+	ci := -1
+	for i, cv := range gn.CVs {
+		if cv == pr.Last {
+			li = i
+		}
+		if cv == pr.Cur {
+			ci = i
+		}
+	}
+	if li < 0 || ci < 0 || li >= len(gn.Matrix) {
+		return -1
+	}
+	return gn.Matrix[li][ci]
+}
+
+// SeqName returns the file name for sequence i
+func (gn *Gen) SeqName(i int) string {
+	return fmt.Sprintf("%v_%05d", gn.Prefix, i)
+}
+
+// WriteSeqs writes each sequence to a separate file in SeqsDir
+// and the expected label timing of the CVs to a file of the same name in TimesDir
+func (gn *Gen) WriteSeqs() {
+	for i, seq := range gn.Seqs {
+		fn := gn.SeqName(i)
+		f, err := os.Create(gn.SeqsDir + fn)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		f.WriteString(strings.Join(seq, " ") + "\n")
+		f.Close()
+
+		if gn.TimesDir == "" {
+			continue
+		}
+		f, err = os.Create(gn.TimesDir + fn + ".txt")
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		st := 0.0
+		for _, cv := range seq {
+			end := st + gn.CVMs
+			f.WriteString(fmt.Sprintf("%.6f\t%.6f\t%v\n", st/1000, end/1000, cv))
+			st = end + gn.GapMs
+		}
+		f.Close()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// 		Gui
+
+// ConfigGui configures the GoGi gui interface for this Gen
+func (gn *Gen) ConfigGui() *gi.Window {
+	width := 1600
+	height := 1200
+
+	gi.SetAppName("GenLang")
+	gi.SetAppAbout(`GenLang generates CV sequences with a given transitional probability structure`)
+
+	win := gi.NewMainWindow("one", "GenLang ...", width, height)
+
+	vp := win.WinViewport2D()
+	updt := vp.UpdateStart()
+
+	mfr := win.SetMainFrame()
+
+	tbar := gi.AddNewToolBar(mfr, "tbar")
+	tbar.SetStretchMaxWidth()
+
+	split := gi.AddNewSplitView(mfr, "split")
+	split.Dim = gi.X
+	split.SetStretchMaxWidth()
+	split.SetStretchMaxHeight()
+
+	sv := giv.AddNewStructView(split, "sv")
+	sv.SetStruct(gn)
+	gn.StructView = sv
+
+	tbar.AddAction(gi.ActOpts{Label: "Reset", Icon: "new", Tooltip: ""}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.Reset()
+			sv.UpdateFields()
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Load Words", Icon: "new", Tooltip: "load the words and frequencies from WordFile"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.LoadWords()
+			sv.UpdateFields()
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Load Matrix", Icon: "new", Tooltip: "load the CVs and transition matrix from MatrixFile"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.LoadMatrix()
+			sv.UpdateFields()
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Gen From Words", Icon: "new", Tooltip: "Generate NSeqs sequences of NWords words chosen by frequency"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.GenFromWords()
+			sv.UpdateFields()
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Gen From Matrix", Icon: "new", Tooltip: "Generate NSeqs sequences of NCVs CVs from the transition matrix"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.GenFromMatrix()
+			sv.UpdateFields()
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Report", Icon: "new", Tooltip: "report the realized transitional probabilities of the generated sequences"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.Report()
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Write Seqs", Icon: "new", Tooltip: "write each sequence and its expected label timing to a file"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.WriteSeqs()
+		})
+
+	vp.UpdateEndNoSig(updt)
+
+	// main menu
+	appnm := gi.AppName()
+	mmen := win.MainMenu
+	mmen.ConfigMenus([]string{appnm, "File", "Edit", "Window"})
+
+	amen := win.MainMenu.ChildByName(appnm, 0).(*gi.Action)
+	amen.Menu.AddAppMenu(win)
+
+	emen := win.MainMenu.ChildByName("Edit", 1).(*gi.Action)
+	emen.Menu.AddCopyCutPaste(win)
+
+	vp.UpdateEndNoSig(updt)
+
+	win.MainMenuUpdated()
+	return win
+}
+
+func mainrun() {
+	Gen := NewGen()
+	win := Gen.ConfigGui()
+	win.StartEventLoop()
+}
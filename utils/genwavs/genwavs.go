@@ -2,11 +2,12 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// This code generates wav files by calling gnuspeech
+// This code generates wav files, and the label files with the CV times, with the formant synthesizer
 package main
 
 import (
 	"bufio"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"math/rand"
@@ -15,6 +16,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/ccnlab/statlearn/synth"
+	"github.com/emer/auditory/sound"
 	_ "github.com/emer/etable/etview" // include to get gui views
 	"github.com/goki/gi/gi"
 	"github.com/goki/gi/gimain"
@@ -31,42 +34,26 @@ func main() {
 }
 
 type Gen struct {
-	StructView   *giv.StructView `view:"-" desc:"the params viewer"`
-	VoicesPath   string          `desc:"voice files are the voice config files that gnu_speech uses"`
-	ControlsPath string          `desc:"control files are the trm_control_model config files that gnu_speech uses"`
-	SeqsPath     string          `desc:"seqs are the cv sequences, pa bi ku go etc"`
-	WavsPath     string          `desc:"where to write the wavs file to"`
-	VoiceFiles   []string        `view:"no-inline"`
-	ControlFiles []string        `view:"no-inline"`
-	SeqFiles     []string        `view:"no-inline"`
-	WavFiles     []string        `view:"no-inline"`
-	NWavs        int             `desc:"the number of wav files to generate"`
+	StructView *giv.StructView `view:"-" desc:"the params viewer"`
+	SeqsPath   string          `desc:"seqs are the cv sequences, pa bi ku go etc"`
+	WavsPath   string          `desc:"where to write the wavs file to"`
+	TimesPath  string          `desc:"where to write the label files with the CV start and end times to"`
+	Synth      synth.Synth     `desc:"the formant synthesizer parameters"`
+	F0s        []float64       `desc:"the voices - each wav is rendered with one of these fundamental frequencies, chosen at random, declining to 5/6 of it by the end of the sequence"`
+	SeqFiles   []string        `view:"no-inline"`
+	WavFiles   []string        `view:"no-inline"`
+	NWavs      int             `desc:"the number of wav files to generate"`
 }
 
 func NewGen() *Gen {
 	g := Gen{}
 
-	g.VoicesPath = "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/voices"
-	g.ControlsPath = "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/controls"
 	g.SeqsPath = "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/seqs"
 	g.WavsPath = "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/wavs/"
+	g.TimesPath = "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/times/"
 	g.NWavs = 110
-
-	cfiles, cerr := ioutil.ReadDir(g.ControlsPath)
-	if cerr != nil {
-		log.Fatal(cerr)
-	}
-	for _, f := range cfiles {
-		g.ControlFiles = append(g.ControlFiles, f.Name())
-	}
-
-	vfiles, verr := ioutil.ReadDir(g.VoicesPath)
-	if verr != nil {
-		log.Fatal(verr)
-	}
-	for _, f := range vfiles {
-		g.VoiceFiles = append(g.VoiceFiles, f.Name())
-	}
+	g.Synth.Defaults()
+	g.F0s = []float64{100, 120, 140, 180, 220}
 
 	sfiles, serr := ioutil.ReadDir(g.SeqsPath)
 	if serr != nil {
@@ -87,67 +74,60 @@ func (gn *Gen) Reset() {
 
 }
 
-// GenWavs generates wav files using a voice file and a control file previously generated
+// GenWavs generates wav files by rendering randomly chosen sequences with the synthesizer,
+// in a randomly chosen voice, and writes the labels (Audacity format, seconds) of the CVs
 func (gn *Gen) GenWavs() {
-	arg1 := "-c"
-	arg2 := "/Users/rohrlich/gnuspeech_sa-master/data/en" // path to the configuration file, dictionary etc
-	arg3 := "-p"
-	arg4 := "trm_param_file.txt"
-	arg5 := "-o"
-	seqtxt := ""
-
 	for idx := 0; idx < gn.NWavs; idx++ {
-		i := rand.Intn(len(gn.VoiceFiles))
-		fvoice := gn.VoiceFiles[i]
-		vs := strings.Replace(fvoice, "voice_", "", 1)
-
-		j := rand.Intn(len(gn.ControlFiles))
-		fcontrol := gn.ControlFiles[j]
-		cs := strings.Replace(fcontrol, "control_", "", 1)
+		f0 := gn.F0s[rand.Intn(len(gn.F0s))]
+		vs := "f0" + strconv.Itoa(int(f0))
 
 		k := rand.Intn(len(gn.SeqFiles))
 		ks := gn.SeqFiles[k]
-		fseq := "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/seqs/" + gn.SeqFiles[k]
-
-		fp, err := os.Open(fseq)
+		seqtxt, err := gn.ReadSeq(ks)
 		if err != nil {
 			log.Fatal(err)
 		}
-		defer fp.Close() // we will be done with the file within this function
-		scanner := bufio.NewScanner(fp)
-		scanner.Split(bufio.ScanLines)
-		for scanner.Scan() {
-			seqtxt = scanner.Text()
-		}
 
-		// the gnuspeech software expects a configuration file of specific name - easy just to copy file to that name
-		fc := "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/controls/" + fcontrol
-		copyCmd := exec.Command("cp", fc, "/Users/rohrlich/gnuspeech_sa-master/data/en/trm_control_model.config")
-		copyCmd.Stdout = os.Stdout
-		copyCmd.Stderr = os.Stderr
-		err = copyCmd.Run()
+		sy := gn.Synth
+		sy.F0 = f0
+		sy.F0End = f0 * 5 / 6
+		vals, labels, err := sy.Render(strings.Fields(seqtxt))
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		// the control file is expecting a voice configuration file of specific name - easy just to copy file to that name
-		fv := "/Users/rohrlich/go/src/github.com/ccnlab/lang-acq/voices/" + fvoice
-		copyCmd = exec.Command("cp", fv, "/Users/rohrlich/gnuspeech_sa-master/data/en/voice_config.config")
-		copyCmd.Stdout = os.Stdout
-		copyCmd.Stderr = os.Stderr
-		err = copyCmd.Run()
+		fn := ks + "_synth_" + vs
+		snd := sound.Wave{Buf: sy.IntBuffer(vals)}
+		err = snd.WriteWave(gn.WavsPath + fn + ".wav")
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		fout := gn.WavsPath + ks + "_" + cs + "_" + vs + ".wav"
-		cmd := exec.Command("/Users/rohrlich/gnuspeech_sa-master/build/gnuspeech_sa", arg1, arg2, arg3, arg4, arg5, fout, seqtxt)
-		cmd.Stdout = os.Stdout
-		err = cmd.Run()
+		f, err := os.Create(gn.TimesPath + fn + ".txt")
 		if err != nil {
 			log.Fatal(err)
 		}
+		for _, l := range labels {
+			f.WriteString(fmt.Sprintf("%.6f\t%.6f\t%v\n", l.Start, l.End, l.Name))
+		}
+		f.Close()
+	}
+}
+
+// ReadSeq returns the cv sequence of the sequence file
+func (gn *Gen) ReadSeq(fn string) (string, error) {
+	seqtxt := ""
+	fp, err := os.Open(gn.SeqsPath + "/" + fn)
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close() // we will be done with the file within this function
+	scanner := bufio.NewScanner(fp)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		seqtxt = scanner.Text()
 	}
+	return seqtxt, nil
 }
 
 // SplitWavs
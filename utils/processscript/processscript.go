@@ -6,9 +6,13 @@ package main
 
 import (
 	"bufio"
+	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -22,20 +26,32 @@ import (
 // this is the stub main for gogi that calls our actual
 // mainrun function, at end of file
 func main() {
-	gimain.Main(func() {
-		mainrun()
-	})
+	if len(os.Args) > 1 {
+		NewGen().CmdArgs() // any args = no gui
+	} else {
+		gimain.Main(func() {
+			mainrun()
+		})
+	}
 }
 
 type Gen struct {
-	TranscriptDir   string          `view:"no-inline" desc:"directory of transcripts"`
-	TranscriptFile  string          `view:"no-inline" desc:"a single transcript file"`
-	DirOut          string          `view:"no-inline" desc:"directory of where to write files"`
-	TranscriptFiles []string        `view:"no-inline" desc:"list of transcript files in TranscriptDir"`
-	Seqs            []string        `view:"no-inline" desc:"the generated word sequences"`
-	NSeqs           int             `desc:"the number of sequences to create"`
-	Separator       string          `view:"no-inline" desc:"between words string"`
-	StructView      *giv.StructView `view:"-" desc:"the params viewer"`
+	TranscriptDir   string              `view:"no-inline" desc:"directory of transcripts"`
+	TranscriptFile  string              `view:"no-inline" desc:"a single transcript file"`
+	DirOut          string              `view:"no-inline" desc:"directory of where to write files"`
+	TranscriptFiles []string            `view:"no-inline" desc:"list of transcript files in TranscriptDir"`
+	Seqs            []string            `view:"no-inline" desc:"the generated word sequences"`
+	NSeqs           int                 `desc:"the number of sequences to create"`
+	Separator       string              `view:"no-inline" desc:"between words string"`
+	Speakers        []string            `desc:"the speaker tiers of the utterances to keep, e.g. CDS, MOT, FAT"`
+	DictFile        string              `view:"no-inline" desc:"pronunciation dictionary in CMU format - each line is a word and its phones separated by spaces, vowels marked by a stress digit or listed in Vowels"`
+	Vowels          []string            `desc:"the vowel phones of the dictionary, without stress digits - each syllable has one vowel"`
+	ListFile        string              `view:"no-inline" desc:"name of the file, in DirOut, listing the sequence files written, for the SndList of a stimulus set"`
+	SeqNames        []string            `view:"no-inline" desc:"the names of the sequence files written"`
+	NoPron          int                 `inactive:"+" desc:"number of utterances skipped because a word is not in the dictionary"`
+	Dict            map[string][]string `view:"-" desc:"the phones of each word in the dictionary"`
+	Onsets          map[string]bool     `view:"-" desc:"the legal syllable onsets - the consonants that start the words of the dictionary"`
+	StructView      *giv.StructView     `view:"-" desc:"the params viewer"`
 }
 
 func NewGen() *Gen {
@@ -43,12 +59,18 @@ func NewGen() *Gen {
 	g.TranscriptFile = "/Users/rohrlich/gnuspeech_sa-master/generated/temp.txt"
 	g.TranscriptDir = "/Users/rohrlich/gnuspeech_sa-master/generated/ChildesNuffieldTranscripts"
 	g.DirOut = "/Users/rohrlich/gnuspeech_sa-master/generated/ChildesNuffieldUtterances/"
+	g.Speakers = []string{"CDS"}
+	g.DictFile = "/Users/rohrlich/gnuspeech_sa-master/generated/cmudict.dict"
+	g.Vowels = []string{"AA", "AE", "AH", "AO", "AW", "AY", "EH", "ER", "EY", "IH", "IY", "OW", "OY", "UH", "UW"}
+	g.ListFile = "seqs.txt"
 	return &g
 }
 
 // Reset clears the seqs and words slices
 func (gn *Gen) Reset() {
 	gn.Seqs = gn.Seqs[:0]
+	gn.SeqNames = gn.SeqNames[:0]
+	gn.NoPron = 0
 }
 
 func check(e error) {
@@ -59,10 +81,8 @@ func check(e error) {
 
 // WriteSeqs writes each sequence to a separate file
 func (gn *Gen) WriteSeqs() {
-	strs := strings.Split(gn.TranscriptFile, ".")
-	file := strs[0]
-	last := strings.LastIndex(file, "/")
-	prefix := file[last+1 : len(file)]
+	tf := gn.TranscriptFile
+	prefix := strings.TrimSuffix(filepath.Base(tf), filepath.Ext(tf)) // dots in the directory are not the extension
 
 	for i, s := range gn.Seqs {
 		a := strconv.Itoa(i)
@@ -82,6 +102,18 @@ func (gn *Gen) WriteSeqs() {
 		f, err := os.Create(fn)
 		check(err)
 		f.Write([]byte(s))
+		f.Close()
+		gn.SeqNames = append(gn.SeqNames, prefix+id)
+	}
+}
+
+// WriteList writes the names of all of the sequence files written to ListFile in DirOut
+func (gn *Gen) WriteList() {
+	f, err := os.Create(gn.DirOut + gn.ListFile)
+	check(err)
+	defer f.Close()
+	for _, nm := range gn.SeqNames {
+		f.WriteString(nm + "\n")
 	}
 }
 
@@ -107,6 +139,211 @@ func (gn *Gen) SplitTranscript() {
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////////////////
+// 		Corpus pipeline - normalize, phonemize, syllabify
+
+// Utterances returns the utterances of the Speakers in the transcript file, with the speaker tier removed
+func (gn *Gen) Utterances(fn string) []string {
+	fp, err := os.Open(fn)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer fp.Close()
+
+	var utts []string
+	scanner := bufio.NewScanner(fp)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		txt := scanner.Text()
+		for _, sp := range gn.Speakers {
+			if strings.HasPrefix(txt, "*"+sp+":") {
+				utts = append(utts, strings.TrimSpace(strings.TrimPrefix(txt, "*"+sp+":")))
+				break
+			}
+		}
+	}
+	return utts
+}
+
+var (
+	chatBracket = regexp.MustCompile(`\[[^\]]*\]|\x15[^\x15]*\x15`) // [/], [* m], [= text] and time marks
+	chatPunct   = regexp.MustCompile(`[.,?!;:"<>+/_]`)              // utterance terminators, overlaps, compounds
+	chatSuffix  = regexp.MustCompile(`@[a-z:]*$`)                   // special form markers, e.g. ba@b
+)
+
+// Normalize returns the words of a CHAT utterance in lower case, with the codes and punctuation removed.
+// Returns nil if the utterance has unintelligible words (xxx, yyy, www) as the boundaries would be unknown.
+func (gn *Gen) Normalize(utt string) []string {
+	utt = strings.ToLower(utt)
+	utt = chatBracket.ReplaceAllString(utt, " ")
+	utt = strings.NewReplacer("(", "", ")", "").Replace(utt) // incomplete words, e.g. (be)cause
+	utt = chatPunct.ReplaceAllString(utt, " ")
+	var words []string
+	for _, w := range strings.Fields(utt) {
+		if w == "xxx" || w == "yyy" || w == "www" {
+			return nil
+		}
+		if strings.HasPrefix(w, "&") || strings.HasPrefix(w, "0") || strings.HasPrefix(w, "#") {
+			continue // fillers, fragments, omitted words and pauses
+		}
+		w = chatSuffix.ReplaceAllString(w, "")
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// IsVowel returns true if the phone, without its stress digit, is in Vowels
+func (gn *Gen) IsVowel(ph string) bool {
+	for _, v := range gn.Vowels {
+		if v == ph {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadDict loads the pronunciation dictionary, keeping the first pronunciation of each word,
+// and the legal onsets from the consonants at the start of the words
+func (gn *Gen) LoadDict() error {
+	fp, err := os.Open(gn.DictFile)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer fp.Close()
+
+	gn.Dict = make(map[string][]string)
+	gn.Onsets = map[string]bool{"": true}
+	scanner := bufio.NewScanner(fp)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		txt := scanner.Text()
+		if strings.HasPrefix(txt, ";;;") {
+			continue
+		}
+		flds := strings.Fields(txt)
+		if len(flds) < 2 {
+			continue
+		}
+		w := strings.ToLower(flds[0])
+		if _, has := gn.Dict[w]; has || strings.HasSuffix(w, ")") { // alternate pronunciations, e.g. the(2)
+			continue
+		}
+		phs := make([]string, 0, len(flds)-1)
+		for _, ph := range flds[1:] {
+			if ph == "#" {
+				break // comment
+			}
+			phs = append(phs, strings.ToUpper(strings.TrimRight(ph, "012")))
+		}
+		gn.Dict[w] = phs
+		for i, ph := range phs {
+			if gn.IsVowel(ph) {
+				gn.Onsets[strings.Join(phs[:i], " ")] = true
+				break
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Phonemize returns the phones of each word, false if any word is not in the dictionary
+func (gn *Gen) Phonemize(words []string) ([][]string, bool) {
+	prons := make([][]string, len(words))
+	for i, w := range words {
+		phs, has := gn.Dict[w]
+		if !has {
+			return nil, false
+		}
+		prons[i] = phs
+	}
+	return prons, true
+}
+
+// Syllabify splits the phones of a word into syllables, one vowel per syllable, using the maximal onset
+// principle - the consonants between two vowels go to the second syllable, as many as make a legal onset
+func (gn *Gen) Syllabify(phs []string) [][]string {
+	var vows []int
+	for i, ph := range phs {
+		if gn.IsVowel(ph) {
+			vows = append(vows, i)
+		}
+	}
+	if len(vows) == 0 {
+		return [][]string{phs}
+	}
+	var syls [][]string
+	st := 0
+	for k := 0; k < len(vows)-1; k++ {
+		cst := vows[k] + 1 // the consonants between this vowel and the next
+		cend := vows[k+1]
+		brk := cend
+		for b := cst; b < cend; b++ {
+			if gn.Onsets[strings.Join(phs[b:cend], " ")] {
+				brk = b
+				break
+			}
+		}
+		syls = append(syls, phs[st:brk])
+		st = brk
+	}
+	return append(syls, phs[st:])
+}
+
+// SeqString returns the sequence of an utterance in the SeqsPath format - the syllables separated by spaces,
+// each syllable the phones run together in lower case, and a "." after the last syllable of each word,
+// which the sim removes when it splits the sequence
+func (gn *Gen) SeqString(prons [][]string) string {
+	var syls []string
+	for _, phs := range prons {
+		ws := gn.Syllabify(phs)
+		for i, syl := range ws {
+			s := strings.ToLower(strings.Join(syl, ""))
+			if i == len(ws)-1 {
+				s += "."
+			}
+			syls = append(syls, s)
+		}
+	}
+	return strings.Join(syls, " ")
+}
+
+// ProcessTranscript turns the utterances of the transcript into sequences of syllables and adds them to Seqs
+func (gn *Gen) ProcessTranscript() {
+	if gn.Dict == nil {
+		if err := gn.LoadDict(); err != nil {
+			return
+		}
+	}
+	for _, utt := range gn.Utterances(gn.TranscriptFile) {
+		words := gn.Normalize(utt)
+		if len(words) == 0 {
+			continue
+		}
+		prons, ok := gn.Phonemize(words)
+		if !ok {
+			gn.NoPron++
+			continue
+		}
+		gn.Seqs = append(gn.Seqs, gn.SeqString(prons)+"\n")
+	}
+}
+
+// ProcessTranscripts processes each file in TranscriptFiles, writing the sequences of each, and then the list file
+func (gn *Gen) ProcessTranscripts() {
+	for _, f := range gn.TranscriptFiles {
+		gn.TranscriptFile = gn.TranscriptDir + "/" + f
+		gn.ProcessTranscript()
+		gn.WriteSeqs()
+		gn.Seqs = gn.Seqs[:0]
+	}
+	gn.WriteList()
+	fmt.Printf("wrote %d sequences, skipped %d utterances with words not in the dictionary\n", len(gn.SeqNames), gn.NoPron)
+}
+
 // LoadTranscripts
 func (gn *Gen) LoadTranscripts() {
 	files, err := ioutil.ReadDir(gn.TranscriptDir)
@@ -186,6 +423,16 @@ func (gn *Gen) ConfigGui() *gi.Window {
 			gn.WriteSeqs()
 		})
 
+	tbar.AddAction(gi.ActOpts{Label: "Load Dict", Icon: "new", Tooltip: "Load the pronunciation dictionary"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.LoadDict()
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Process Transcripts", Icon: "new", Tooltip: "Normalize, phonemize and syllabify the utterances of each file in TranscriptFiles, and write the sequence files and list file"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			gn.ProcessTranscripts()
+		})
+
 	vp.UpdateEndNoSig(updt)
 
 	// main menu
@@ -210,3 +457,23 @@ func mainrun() {
 	win := Gen.ConfigGui()
 	win.StartEventLoop()
 }
+
+// CmdArgs runs the corpus pipeline without the gui
+func (gn *Gen) CmdArgs() {
+	var speakers string
+	flag.StringVar(&gn.TranscriptDir, "dir", gn.TranscriptDir, "directory of CHILDES transcripts to process")
+	flag.StringVar(&gn.DirOut, "out", gn.DirOut, "directory to write the sequence files and list file to")
+	flag.StringVar(&gn.DictFile, "dict", gn.DictFile, "pronunciation dictionary file")
+	flag.StringVar(&gn.ListFile, "list", gn.ListFile, "name of the list of sequence files, written in the out directory")
+	flag.StringVar(&speakers, "speakers", strings.Join(gn.Speakers, ","), "comma separated speaker tiers to keep, e.g. MOT,FAT")
+	flag.Parse()
+	gn.Speakers = strings.Split(speakers, ",")
+	if !strings.HasSuffix(gn.DirOut, "/") {
+		gn.DirOut += "/"
+	}
+	if err := gn.LoadDict(); err != nil {
+		os.Exit(1)
+	}
+	gn.LoadTranscripts()
+	gn.ProcessTranscripts()
+}